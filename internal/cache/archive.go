@@ -0,0 +1,452 @@
+package cache
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// ArchivesDirName is the directory in the cache holding compressed cache entries.
+// Archives are named after the content hash of the template files, so that entries
+// with the same files share one archive.
+const ArchivesDirName = "archives"
+
+// archiveExtensions maps compression settings to archive file extensions
+var archiveExtensions = map[string]string{
+	config.CompressionGzip: ".tar.gz",
+	config.CompressionZstd: ".tar.zst",
+}
+
+// archivePath returns the path of an archive in the cache
+func (m *Manager) archivePath(name string) string {
+	return filepath.Join(m.cacheDir, ArchivesDirName, name)
+}
+
+// indexPath returns the path of the file index stored next to an archive
+func (m *Manager) indexPath(archive string) string {
+	return m.archivePath(archiveHash(archive) + ".json")
+}
+
+// archiveHash returns the content hash an archive is named after
+func archiveHash(archive string) string {
+	for _, ext := range archiveExtensions {
+		if hash, ok := strings.CutSuffix(archive, ext); ok {
+			return hash
+		}
+	}
+	return archive
+}
+
+// archived reports whether a cache entry is read from its archive.
+// An expanded directory, e.g. a fresh download not compressed yet, takes precedence.
+func (m *Manager) archived(key string, info *TemplateCacheInfo) bool {
+	if info.Archive == "" {
+		return false
+	}
+	_, err := os.Stat(m.GetTemplateCachePath(key))
+	return os.IsNotExist(err)
+}
+
+// archiveReferenced reports whether any cache entry is stored in an archive
+func archiveReferenced(templates map[string]TemplateCacheInfo, archive string) bool {
+	for _, info := range templates {
+		if info.Archive == archive {
+			return true
+		}
+	}
+	return false
+}
+
+// removeArchive removes an archive and its file index
+func (m *Manager) removeArchive(archive string) error {
+	for _, file := range []string{m.archivePath(archive), m.indexPath(archive)} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// Compression returns the compression of new cache entries, empty or none for expanded entries
+func (m *Manager) Compression() string {
+	return m.compression
+}
+
+// CompressEntry packs the template files of an expanded cache entry into a compressed
+// archive when cache compression is enabled, and removes the expanded directory.
+// The git history is not archived, as entries with the same files share an archive:
+// compressed entries are downloaded afresh on update and have no clone, see EntryClone.
+func (m *Manager) CompressEntry(key string) error {
+	ext, ok := archiveExtensions[m.compression]
+	if !ok {
+		return nil
+	}
+
+	dir := m.GetTemplateCachePath(key)
+	hash, err := HashDirectory(dir)
+	if err != nil {
+		return err
+	}
+	index, err := BuildFileManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	archive := strings.TrimPrefix(hash, "sha256:") + ext
+	if err := os.MkdirAll(m.archivePath(""), 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if err := writeAtomic(m.archivePath(archive), func(w io.Writer) error {
+		return packDirectory(w, dir, m.compression)
+	}); err != nil {
+		return fmt.Errorf("failed to compress %s: %w", key, err)
+	}
+	if err := writeAtomic(m.indexPath(archive), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(index)
+	}); err != nil {
+		return fmt.Errorf("failed to write archive index: %w", err)
+	}
+
+	err = m.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		info, exists := templates[key]
+		if !exists {
+			return fmt.Errorf("template not cached")
+		}
+		info.Archive = archive
+		templates[key] = info
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove expanded cache entry: %w", err)
+	}
+	return nil
+}
+
+// writeAtomic writes a file through a temporary file renamed into place
+func writeAtomic(target string, write func(w io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), target)
+}
+
+// packDirectory writes the files of dir, without the git directory, as a compressed tar archive.
+// Files are added in the order HashDirectory hashes them.
+func packDirectory(w io.Writer, dir, compression string) error {
+	var compressor io.WriteCloser
+	var err error
+	switch compression {
+	case config.CompressionZstd:
+		compressor, err = zstd.NewWriter(w)
+	default:
+		compressor, err = gzip.NewWriterLevel(w, gzip.BestCompression)
+	}
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(compressor)
+	err = filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == dir {
+			return nil
+		}
+		if info.IsDir() && filepath.Base(filePath) == ".git" {
+			return filepath.SkipDir
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		return addFileToTar(tw, filePath, filepath.ToSlash(relPath), info)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return compressor.Close()
+}
+
+// openArchive opens an archive in the cache for reading
+func (m *Manager) openArchive(archive string) (*tar.Reader, func(), error) {
+	file, err := os.Open(m.archivePath(archive))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reader io.Reader
+	var closeReader func()
+	switch {
+	case strings.HasSuffix(archive, archiveExtensions[config.CompressionZstd]):
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		reader, closeReader = decoder, decoder.Close
+	default:
+		decoder, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		reader, closeReader = decoder, func() { decoder.Close() }
+	}
+
+	return tar.NewReader(reader), func() {
+		closeReader()
+		file.Close()
+	}, nil
+}
+
+// ArchiveIndex returns the files of an archived cache entry with their content hashes,
+// read from the index stored next to the archive. It returns nil for expanded entries.
+func (m *Manager) ArchiveIndex(key string) (FileManifest, error) {
+	root := m.lookupCache(key)
+	info, err := root.cacheInfo(key)
+	if err != nil {
+		return nil, err
+	}
+	if !root.archived(key, info) {
+		return nil, nil
+	}
+
+	return root.readIndex(info.Archive)
+}
+
+// readIndex reads the file index of an archive
+func (m *Manager) readIndex(archive string) (FileManifest, error) {
+	data, err := os.ReadFile(m.indexPath(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive index: %w", err)
+	}
+
+	var index FileManifest
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse archive index: %w", err)
+	}
+	return index, nil
+}
+
+// extracted holds the temporary directories archived cache entries are extracted to until released
+var extracted sync.Map
+
+// EntryPath returns the directory holding the files of a cache entry.
+// Archived entries are extracted to a new temporary directory, which carries the archive
+// index as file manifest and is removed by the returned function. Releasing an expanded
+// entry does nothing.
+func (m *Manager) EntryPath(key string) (string, func(), error) {
+	root := m.lookupCache(key)
+	info, err := root.cacheInfo(key)
+	if err != nil || !root.archived(key, info) {
+		return root.GetTemplateCachePath(key), func() {}, nil
+	}
+
+	dir, err := root.extractArchive(info.Archive)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to extract %s: %w", info.Template, err)
+	}
+	extracted.Store(dir, struct{}{})
+
+	return dir, func() {
+		os.RemoveAll(dir)
+		extracted.Delete(dir)
+	}, nil
+}
+
+// extractArchive extracts an archive to a new temporary directory
+func (m *Manager) extractArchive(archive string) (string, error) {
+	index, err := m.readIndex(archive)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "pick-your-go-template-*")
+	if err != nil {
+		return "", err
+	}
+
+	tr, closeArchive, err := m.openArchive(archive)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	defer closeArchive()
+
+	if err := extractTar(tr, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	// The extracted directory verifies like an expanded cache entry
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	data, err := json.Marshal(index)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, ".git", fileManifestName), data, 0644)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// extractTar extracts the files of a tar archive into dir.
+// Paths leaving dir and files placed through symlinks are rejected.
+func extractTar(tr *tar.Reader, dir string) error {
+	symlinks := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		relPath := path.Clean(header.Name)
+		if path.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, "../") {
+			return fmt.Errorf("unexpected file %s in archive", header.Name)
+		}
+		for parent := relPath; parent != "."; parent = path.Dir(parent) {
+			if symlinks[parent] {
+				return fmt.Errorf("file %s in archive is placed through a symlink", header.Name)
+			}
+		}
+
+		if err := extractEntry(tr, header, filepath.Join(dir, filepath.FromSlash(relPath))); err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeSymlink {
+			symlinks[relPath] = true
+		}
+	}
+}
+
+// RemoveExtracted removes the directories of archived cache entries that were not released,
+// e.g. when the process is interrupted
+func RemoveExtracted() {
+	extracted.Range(func(dir, _ any) bool {
+		os.RemoveAll(dir.(string))
+		extracted.Delete(dir)
+		return true
+	})
+}
+
+// hashArchive returns the content hash of the files in an archive, as HashDirectory
+// returns it for the directory the archive was packed from
+func (m *Manager) hashArchive(archive string) (string, error) {
+	tr, closeArchive, err := m.openArchive(archive)
+	if err != nil {
+		return "", err
+	}
+	defer closeArchive()
+
+	hash := sha256.New()
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg:
+			fmt.Fprintf(hash, "%s\x00%d\x00", path.Clean(header.Name), header.Size)
+			if _, err := io.Copy(hash, tr); err != nil {
+				return "", err
+			}
+		case tar.TypeSymlink:
+			fmt.Fprintf(hash, "%s\x00%d\x00%s", path.Clean(header.Name), len(header.Linkname), header.Linkname)
+		}
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// manifestFromArchive hashes every file of an archive like BuildFileManifest
+func (m *Manager) manifestFromArchive(archive string) (FileManifest, error) {
+	tr, closeArchive, err := m.openArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArchive()
+
+	manifest := make(FileManifest)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return manifest, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.New()
+		switch header.Typeflag {
+		case tar.TypeReg:
+			if _, err := io.Copy(hash, tr); err != nil {
+				return nil, err
+			}
+		case tar.TypeSymlink:
+			fmt.Fprintf(hash, "symlink\x00%s", header.Linkname)
+		default:
+			continue
+		}
+		manifest[path.Clean(header.Name)] = "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	}
+}
+
+// EntryHash returns the content hash of the template files of a cache entry, see HashDirectory
+func (m *Manager) EntryHash(key string) (string, error) {
+	root := m.lookupCache(key)
+	if info, err := root.cacheInfo(key); err == nil && root.archived(key, info) {
+		hash, err := root.hashArchive(info.Archive)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash %s: %w", info.Archive, err)
+		}
+		return hash, nil
+	}
+
+	return HashDirectory(root.GetTemplateCachePath(key))
+}
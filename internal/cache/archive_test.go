@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// TestCompressEntry tests that archived entries read, hash and verify like expanded ones
+func TestCompressEntry(t *testing.T) {
+	files := map[string]string{
+		"main.go":             "package main\n",
+		"templates/demo/a.go": "package demo\n",
+		".git/HEAD":           "ref: refs/heads/main\n",
+	}
+
+	for _, compression := range []string{config.CompressionGzip, config.CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			manager := newTestManager(t, t.TempDir())
+			manager.compression = compression
+
+			source := Source{Repository: "https://github.com/example/demo", Ref: "main"}
+			key := writeTestEntry(t, manager, source, files)
+			expectedHash, err := HashDirectory(manager.GetTemplateCachePath(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := manager.CompressEntry(key); err != nil {
+				t.Fatalf("CompressEntry failed: %v", err)
+			}
+			if _, err := os.Stat(manager.GetTemplateCachePath(key)); !os.IsNotExist(err) {
+				t.Errorf("expected the expanded entry to be removed")
+			}
+			if !manager.Exists(key) {
+				t.Fatalf("expected the archived entry to exist")
+			}
+
+			hash, err := manager.EntryHash(key)
+			if err != nil || hash != expectedHash {
+				t.Errorf("expected hash %s, got %s (%v)", expectedHash, hash, err)
+			}
+
+			index, err := manager.ArchiveIndex(key)
+			if err != nil || len(index) != 2 {
+				t.Errorf("expected an index of 2 files, got %v (%v)", index, err)
+			}
+
+			dir, release, err := manager.EntryPath(key)
+			if err != nil {
+				t.Fatalf("EntryPath failed: %v", err)
+			}
+			content, err := os.ReadFile(filepath.Join(dir, "templates", "demo", "a.go"))
+			if err != nil || string(content) != files["templates/demo/a.go"] {
+				t.Errorf("unexpected extracted content %q (%v)", content, err)
+			}
+			if report, err := VerifyDirectory(dir); err != nil || !report.OK() {
+				t.Errorf("expected the extracted entry to verify, got %v (%v)", report, err)
+			}
+			release()
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Errorf("expected the extracted entry to be removed on release")
+			}
+
+			if report, err := manager.VerifyEntry(key); err != nil || !report.OK() {
+				t.Errorf("expected the archived entry to verify, got %v (%v)", report, err)
+			}
+			info, _ := manager.GetCacheInfo(key)
+			if err := os.WriteFile(manager.archivePath(info.Archive), []byte("garbage"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if report, err := manager.VerifyEntry(key); err != nil || !report.Damaged {
+				t.Errorf("expected a damaged archive to be reported, got %v (%v)", report, err)
+			}
+
+			if err := manager.ClearTemplateCache(key); err != nil {
+				t.Fatalf("ClearTemplateCache failed: %v", err)
+			}
+			if _, err := os.Stat(manager.archivePath(info.Archive)); !os.IsNotExist(err) {
+				t.Errorf("expected the unreferenced archive to be removed")
+			}
+		})
+	}
+}
+
+// TestPruneSharedArchive tests that an archive shared by entries counts once towards the cache size
+func TestPruneSharedArchive(t *testing.T) {
+	manager := newTestManager(t, t.TempDir())
+	manager.compression = config.CompressionGzip
+	files := map[string]string{"main.go": "package main\n", ".git/HEAD": "ref: refs/heads/main\n"}
+
+	var keys []string
+	for _, ref := range []string{"main", "v1.0.0"} {
+		key := writeTestEntry(t, manager, Source{Repository: "https://github.com/example/demo", Ref: ref}, files)
+		if err := manager.CompressEntry(key); err != nil {
+			t.Fatalf("CompressEntry failed: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	size, err := manager.GetEntrySize(keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := manager.Prune(PruneOptions{MaxSize: size})
+	if err != nil || len(pruned) != 0 {
+		t.Errorf("expected the cache to fit with the archive counted once, pruned %+v (%v)", pruned, err)
+	}
+
+	pruned, err = manager.Prune(PruneOptions{MaxSize: size - 1})
+	if err != nil || len(pruned) != 2 {
+		t.Fatalf("expected both entries to be pruned, got %+v (%v)", pruned, err)
+	}
+	if freed := pruned[0].Size + pruned[1].Size; freed != size {
+		t.Errorf("expected %d bytes to be freed, got %d", size, freed)
+	}
+}
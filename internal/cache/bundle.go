@@ -0,0 +1,367 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// bundleManifestName is the first file of a bundle, describing the entries it holds
+	bundleManifestName = "bundle.json"
+	// bundleEntriesDir holds the files of the bundled cache entries, one directory per cache key
+	bundleEntriesDir = "entries"
+	// bundleVersion is the version of the bundle format
+	bundleVersion = 1
+)
+
+// bundleManifest describes the cache entries in a bundle
+type bundleManifest struct {
+	Version int           `json:"version"`
+	Entries []bundleEntry `json:"entries"`
+}
+
+// bundleEntry is a cache entry in a bundle
+type bundleEntry struct {
+	Key  string            `json:"key"`
+	Info TemplateCacheInfo `json:"info"`
+	// Hash is the content hash of the template files, see HashDirectory
+	Hash string `json:"hash"`
+}
+
+// ExportBundle writes the given cache entries with their metadata and content hashes
+// to w as a gzip compressed tar archive, see ImportBundle
+func (m *Manager) ExportBundle(w io.Writer, keys []string) error {
+	manifest := bundleManifest{Version: bundleVersion}
+	for _, key := range keys {
+		info, err := m.GetCacheInfo(key)
+		if err != nil {
+			return fmt.Errorf("cache entry %s: %w", key, err)
+		}
+		label := info.Template + " @ " + info.Source.Ref
+
+		// Corrupted files must not spread to other machines
+		report, err := m.VerifyEntry(key)
+		if err != nil {
+			return err
+		}
+		if !report.OK() {
+			return fmt.Errorf("%s failed verification (%s), run 'pick-your-go templates update' first", label, report)
+		}
+
+		hash, err := m.EntryHash(key)
+		if err != nil {
+			return err
+		}
+
+		exported := *info
+		exported.Path = ""
+		exported.LastUsed = time.Time{}
+		exported.Archive = ""
+		manifest.Entries = append(manifest.Entries, bundleEntry{Key: key, Info: exported, Hash: hash})
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle manifest: %w", err)
+	}
+	header := &tar.Header{Name: bundleManifestName, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	for _, entry := range manifest.Entries {
+		// Archived entries are bundled from their extracted files, with the archive index as manifest
+		dir, release, err := m.EntryPath(entry.Key)
+		if err != nil {
+			return err
+		}
+		err = addDirToTar(tw, dir, path.Join(bundleEntriesDir, entry.Key))
+		release()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return nil
+}
+
+// addDirToTar adds the files of dir, the git directory included, to a tar archive under prefix
+func addDirToTar(tw *tar.Writer, dir, prefix string) error {
+	return filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		return addFileToTar(tw, filePath, path.Join(prefix, filepath.ToSlash(relPath)), info)
+	})
+}
+
+// addFileToTar adds a file, directory or symlink to a tar archive under name
+func addFileToTar(tw *tar.Writer, filePath, name string, info os.FileInfo) error {
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(filePath); err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", filePath, err)
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	// Owners differ between machines
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", filePath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", filePath, err)
+	}
+	return nil
+}
+
+// ImportBundle installs the cache entries of a bundle written by ExportBundle into the cache,
+// replacing entries with the same key. Every entry is checked against the content hash
+// recorded in the bundle before it is installed.
+func (m *Manager) ImportBundle(r io.Reader) ([]TemplateCacheInfo, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	manifest, err := readBundleManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(m.cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(m.cacheDir, ".import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := extractBundle(tr, tempDir, manifest); err != nil {
+		return nil, err
+	}
+
+	// Check every entry before installing any of them
+	for _, entry := range manifest.Entries {
+		hash, err := HashDirectory(filepath.Join(tempDir, entry.Key))
+		if err != nil {
+			return nil, err
+		}
+		if hash != entry.Hash {
+			return nil, fmt.Errorf("%s @ %s in the bundle has content hash %s, expected %s",
+				entry.Info.Template, entry.Info.Source.Ref, hash, entry.Hash)
+		}
+	}
+
+	var imported []TemplateCacheInfo
+	for _, entry := range manifest.Entries {
+		if err := m.installImported(filepath.Join(tempDir, entry.Key), entry); err != nil {
+			return imported, err
+		}
+		imported = append(imported, entry.Info)
+	}
+
+	return imported, nil
+}
+
+// readBundleManifest reads and validates the manifest at the start of a bundle
+func readBundleManifest(tr *tar.Reader) (*bundleManifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if header.Name != bundleManifestName {
+		return nil, fmt.Errorf("not a pick-your-go bundle: %s is missing", bundleManifestName)
+	}
+
+	var manifest bundleManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", bundleManifestName, err)
+	}
+	if manifest.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+
+	for _, entry := range manifest.Entries {
+		// The key names a directory, it must be the key of the recorded source
+		if entry.Key != entry.Info.Source.Key() {
+			return nil, fmt.Errorf("bundle entry %s does not match its source %s@%s",
+				entry.Key, entry.Info.Source.Repository, entry.Info.Source.Ref)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// extractBundle extracts the entry files of a bundle into dir, one directory per cache key.
+// Files outside the bundled entries, and files placed through symlinks, are rejected.
+func extractBundle(tr *tar.Reader, dir string, manifest *bundleManifest) error {
+	keys := make(map[string]bool, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		keys[entry.Key] = true
+		if err := os.MkdirAll(filepath.Join(dir, entry.Key), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", entry.Key, err)
+		}
+	}
+
+	symlinks := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		// Cleaning resolves .. elements, a path leaving the entries directory loses its prefix
+		relPath, ok := strings.CutPrefix(path.Clean(header.Name), bundleEntriesDir+"/")
+		if !ok {
+			return fmt.Errorf("unexpected file %s in bundle", header.Name)
+		}
+		key, _, _ := strings.Cut(relPath, "/")
+		if !keys[key] {
+			return fmt.Errorf("file %s belongs to no bundled entry", header.Name)
+		}
+		for parent := relPath; parent != "."; parent = path.Dir(parent) {
+			if symlinks[parent] {
+				return fmt.Errorf("file %s in bundle is placed through a symlink", header.Name)
+			}
+		}
+
+		if err := extractEntry(tr, header, filepath.Join(dir, filepath.FromSlash(relPath))); err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeSymlink {
+			symlinks[relPath] = true
+		}
+	}
+}
+
+// extractEntry writes the current directory, file or symlink of a tar archive to target
+func extractEntry(tr *tar.Reader, header *tar.Header, target string) error {
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", target, err)
+		}
+	case tar.TypeReg:
+		return extractFile(tr, target, os.FileMode(header.Mode).Perm())
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+		}
+		if err := os.Symlink(header.Linkname, target); err != nil {
+			return fmt.Errorf("failed to create %s: %w", target, err)
+		}
+	default:
+		return fmt.Errorf("unsupported file type of %s", header.Name)
+	}
+	return nil
+}
+
+// extractFile writes the current file of a tar archive to target
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return nil
+}
+
+// installImported moves an extracted bundle entry into the cache and records it in the metadata
+func (m *Manager) installImported(dir string, entry bundleEntry) error {
+	unlock, err := m.LockEntry(entry.Key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cachePath := m.GetTemplateCachePath(entry.Key)
+	if _, err := os.Stat(cachePath); err == nil {
+		// Moved aside into the extraction directory, which is removed afterwards
+		if err := os.Rename(cachePath, dir+".old"); err != nil {
+			return fmt.Errorf("failed to move old cache aside: %w", err)
+		}
+	}
+	if err := os.Rename(dir, cachePath); err != nil {
+		return fmt.Errorf("failed to move %s into the cache: %w", entry.Info.Template, err)
+	}
+
+	err = m.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		archive := templates[entry.Key].Archive
+		info := entry.Info
+		info.Path = cachePath
+		info.Archive = ""
+		templates[entry.Key] = info
+
+		if archive != "" && !archiveReferenced(templates, archive) {
+			return m.removeArchive(archive)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.CompressEntry(entry.Key)
+}
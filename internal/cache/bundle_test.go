@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestEntry caches a fake template with the given files and a file manifest
+func writeTestEntry(t *testing.T, manager *Manager, source Source, files map[string]string) string {
+	t.Helper()
+	key := source.Key()
+	cachePath := manager.GetTemplateCachePath(key)
+
+	for path, content := range files {
+		fullPath := filepath.Join(cachePath, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := WriteFileManifest(cachePath); err != nil {
+		t.Fatalf("WriteFileManifest failed: %v", err)
+	}
+	if err := manager.UpdateCacheSource("demo", source, "abc1234"); err != nil {
+		t.Fatalf("UpdateCacheSource failed: %v", err)
+	}
+
+	return key
+}
+
+// TestBundleRoundTrip tests that exported cache entries are installed by an import on another cache
+func TestBundleRoundTrip(t *testing.T) {
+	exporter := newTestManager(t, t.TempDir())
+	source := Source{Repository: "https://github.com/example/demo", Ref: "v1.0.0"}
+	key := writeTestEntry(t, exporter, source, map[string]string{
+		"main.go":      "package main\n",
+		"cmd/app.go":   "package cmd\n",
+		".git/HEAD":    "ref: refs/heads/main\n",
+		"README.md":    "# demo\n",
+		"config/a.yml": "a: 1\n",
+	})
+
+	var bundle bytes.Buffer
+	if err := exporter.ExportBundle(&bundle, []string{key}); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+
+	importer := newTestManager(t, t.TempDir())
+	imported, err := importer.ImportBundle(bytes.NewReader(bundle.Bytes()))
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if len(imported) != 1 || imported[0].Source != source || imported[0].Commit != "abc1234" {
+		t.Errorf("unexpected imported entries: %+v", imported)
+	}
+
+	if !importer.Exists(key) {
+		t.Fatalf("expected the imported entry to be cached")
+	}
+	if report, err := importer.VerifyEntry(key); err != nil || !report.OK() {
+		t.Errorf("expected the imported entry to verify, got %v (%v)", report, err)
+	}
+	info, _ := importer.GetCacheInfo(key)
+	if info.Path != importer.GetTemplateCachePath(key) {
+		t.Errorf("expected the path of the importing cache, got %s", info.Path)
+	}
+}
+
+// TestImportBundleRejectsTampering tests that bundles with changed files or paths outside the cache fail
+func TestImportBundleRejectsTampering(t *testing.T) {
+	source := Source{Repository: "https://github.com/example/demo", Ref: "main"}
+	manifest := bundleManifest{
+		Version: bundleVersion,
+		Entries: []bundleEntry{{Key: source.Key(), Info: TemplateCacheInfo{Template: "demo", Source: source}, Hash: "sha256:0000"}},
+	}
+
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"Changed content", "entries/" + source.Key() + "/main.go"},
+		{"Path outside the entries", "entries/../../escape.go"},
+		{"Unknown entry", "entries/0123456789abcdef/main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bundle bytes.Buffer
+			gz := gzip.NewWriter(&bundle)
+			tw := tar.NewWriter(gz)
+			data, _ := json.Marshal(manifest)
+			tw.WriteHeader(&tar.Header{Name: bundleManifestName, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+			tw.Write(data)
+			content := []byte("package main\n")
+			tw.WriteHeader(&tar.Header{Name: tt.file, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+			tw.Write(content)
+			tw.Close()
+			gz.Close()
+
+			cacheDir := t.TempDir()
+			manager := newTestManager(t, cacheDir)
+			if _, err := manager.ImportBundle(&bundle); err == nil {
+				t.Errorf("expected the bundle to be rejected")
+			}
+			if manager.Exists(source.Key()) {
+				t.Errorf("expected nothing to be installed")
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(cacheDir), "escape.go")); err == nil {
+				t.Errorf("expected no file to be written outside the cache")
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fileManifestName is the file in the git directory of a cache entry that records
+// the content hash of every template file as it was downloaded
+const fileManifestName = "pick-your-go-files.json"
+
+// FileManifest maps the slash separated path of every file in a cache entry to its content hash
+type FileManifest map[string]string
+
+// IntegrityReport lists how the files of a cache entry differ from its file manifest
+type IntegrityReport struct {
+	Modified []string
+	Missing  []string
+	Extra    []string
+	// NoManifest is set for entries without a file manifest, which cannot be verified
+	NoManifest bool
+	// Damaged is set for archived entries whose archive cannot be read
+	Damaged bool
+}
+
+// OK reports whether the cache entry matches its file manifest
+func (r *IntegrityReport) OK() bool {
+	return !r.NoManifest && !r.Damaged && len(r.Modified) == 0 && len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// String summarizes the report, e.g. "2 modified, 1 missing"
+func (r *IntegrityReport) String() string {
+	if r.NoManifest {
+		return "no file manifest"
+	}
+	if r.Damaged {
+		return "damaged archive"
+	}
+
+	var parts []string
+	for _, part := range []struct {
+		files []string
+		label string
+	}{{r.Modified, "modified"}, {r.Missing, "missing"}, {r.Extra, "extra"}} {
+		if len(part.files) > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", len(part.files), part.label))
+		}
+	}
+	if len(parts) == 0 {
+		return "ok"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// BuildFileManifest hashes every file of a directory, the .git directory is ignored
+func BuildFileManifest(dir string) (FileManifest, error) {
+	manifest := make(FileManifest)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if filepath.Base(path) == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		hash, err := hashFile(path, info)
+		if err != nil {
+			return err
+		}
+		manifest[filepath.ToSlash(relPath)] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", dir, err)
+	}
+
+	return manifest, nil
+}
+
+// hashFile returns the content hash of a file, or of the target of a symlink
+func hashFile(path string, info os.FileInfo) (string, error) {
+	hash := sha256.New()
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "symlink\x00%s", target)
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", err
+		}
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// WriteFileManifest records the files of a cache entry as they are now, so that later
+// changes to the entry can be detected by VerifyDirectory
+func WriteFileManifest(dir string) error {
+	manifest, err := BuildFileManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal file manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".git", fileManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write file manifest: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyDirectory compares the files of a cache entry with its file manifest
+func VerifyDirectory(dir string) (*IntegrityReport, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ".git", fileManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &IntegrityReport{NoManifest: true}, nil
+		}
+		return nil, fmt.Errorf("failed to read file manifest: %w", err)
+	}
+
+	var expected FileManifest
+	if err := json.Unmarshal(data, &expected); err != nil {
+		// A damaged manifest cannot vouch for anything
+		return &IntegrityReport{NoManifest: true}, nil
+	}
+
+	actual, err := BuildFileManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareManifests(expected, actual), nil
+}
+
+// compareManifests lists how the actual files of a cache entry differ from the expected ones
+func compareManifests(expected, actual FileManifest) *IntegrityReport {
+	report := &IntegrityReport{}
+	for path, hash := range expected {
+		actualHash, exists := actual[path]
+		switch {
+		case !exists:
+			report.Missing = append(report.Missing, path)
+		case actualHash != hash:
+			report.Modified = append(report.Modified, path)
+		}
+	}
+	for path := range actual {
+		if _, exists := expected[path]; !exists {
+			report.Extra = append(report.Extra, path)
+		}
+	}
+
+	sort.Strings(report.Modified)
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+
+	return report
+}
+
+// VerifyEntry compares the files of a cache entry, as found by lookups, with its file manifest,
+// or the files of an archived entry with its archive index
+func (m *Manager) VerifyEntry(key string) (*IntegrityReport, error) {
+	root := m.lookupCache(key)
+	info, err := root.cacheInfo(key)
+	if err != nil || !root.archived(key, info) {
+		return VerifyDirectory(root.GetTemplateCachePath(key))
+	}
+
+	expected, err := root.readIndex(info.Archive)
+	if err != nil {
+		return &IntegrityReport{NoManifest: true}, nil
+	}
+	actual, err := root.manifestFromArchive(info.Archive)
+	if err != nil {
+		return &IntegrityReport{Damaged: true}, nil
+	}
+
+	return compareManifests(expected, actual), nil
+}
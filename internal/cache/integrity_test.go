@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestVerifyDirectory tests detecting modified, missing and extra files in a cache entry
+func TestVerifyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":          "package main\n",
+		"internal/app.go":  "package internal\n",
+		"docs/README.md":   "# docs\n",
+		".git/HEAD":        "ref: refs/heads/main\n",
+		"config/app.yaml":  "name: app\n",
+		"scripts/setup.sh": "#!/bin/sh\n",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := VerifyDirectory(dir)
+	if err != nil {
+		t.Fatalf("VerifyDirectory failed: %v", err)
+	}
+	if !report.NoManifest || report.OK() {
+		t.Errorf("expected an entry without a manifest not to verify, got %+v", report)
+	}
+
+	if err := WriteFileManifest(dir); err != nil {
+		t.Fatalf("WriteFileManifest failed: %v", err)
+	}
+	if report, err := VerifyDirectory(dir); err != nil || !report.OK() {
+		t.Fatalf("expected a fresh entry to verify, got %+v (%v)", report, err)
+	}
+
+	// Changes to the git directory are not template files
+	os.WriteFile(filepath.Join(dir, ".git", "ORIG_HEAD"), []byte("x\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package broken\n"), 0644)
+	os.Remove(filepath.Join(dir, "docs", "README.md"))
+	os.WriteFile(filepath.Join(dir, "internal", "extra.go"), []byte("package internal\n"), 0644)
+
+	report, err = VerifyDirectory(dir)
+	if err != nil {
+		t.Fatalf("VerifyDirectory failed: %v", err)
+	}
+	expected := &IntegrityReport{
+		Modified: []string{"main.go"},
+		Missing:  []string{"docs/README.md"},
+		Extra:    []string{"internal/extra.go"},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("VerifyDirectory() = %+v, expected %+v", report, expected)
+	}
+	if got := report.String(); got != "1 modified, 1 missing, 1 extra" {
+		t.Errorf("String() = %q", got)
+	}
+}
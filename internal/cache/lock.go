@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	// lockFileSuffix is appended to the path a lock file guards
+	lockFileSuffix = ".lock"
+	// incompleteMarker is created in the git directory of a cache entry while it is updated in place
+	incompleteMarker = "pick-your-go-incomplete"
+)
+
+// lockPath takes an exclusive advisory lock guarding path, waiting while another process holds it.
+// The returned function releases the lock.
+func lockPath(path string) (func(), error) {
+	return takeLock(path, (*flock.Flock).Lock)
+}
+
+// rlockPath takes a shared advisory lock guarding path, waiting while a process holds the
+// exclusive lock. The returned function releases the lock.
+func rlockPath(path string) (func(), error) {
+	return takeLock(path, (*flock.Flock).RLock)
+}
+
+// takeLock locks the lock file guarding path with the given flock method
+func takeLock(path string, lockFn func(*flock.Flock) error) (func(), error) {
+	lockFile := path + lockFileSuffix
+	for {
+		// Keep the lock file open, so that it can be recognised once the lock is taken
+		file, err := os.OpenFile(lockFile, os.O_RDONLY|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		lock := flock.New(lockFile)
+		err = lockFn(lock)
+		current := sameFile(file, lockFile)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if current {
+			return func() {
+				lock.Unlock()
+			}, nil
+		}
+
+		// The lock file was removed with its entry while waiting, lock the one created in its place
+		lock.Unlock()
+	}
+}
+
+// sameFile reports whether path still names the open file
+func sameFile(file *os.File, path string) bool {
+	held, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(held, current)
+}
+
+// removeLock removes the lock file guarding path. The caller holds the lock,
+// processes waiting for it take a lock on a new file instead.
+func removeLock(path string) {
+	os.Remove(path + lockFileSuffix)
+}
+
+// removeUnusedLock removes the lock file guarding path unless the lock is held
+func removeUnusedLock(path string) {
+	lock := flock.New(path + lockFileSuffix)
+	if locked, err := lock.TryLock(); err != nil || !locked {
+		return
+	}
+	defer lock.Unlock()
+
+	removeLock(path)
+}
+
+// LockEntry takes the install lock of a cache entry, so that only one process downloads
+// or updates it at a time, once no process reads it. The returned function releases the lock.
+func (m *Manager) LockEntry(key string) (func(), error) {
+	if err := os.MkdirAll(m.cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return lockPath(m.GetTemplateCachePath(key))
+}
+
+// RLockEntry takes the read lock of a cache entry, which keeps updates from changing the
+// entry while its files are read. Readers share the lock, LockEntry waits for all of them.
+// The returned function releases the lock.
+func (m *Manager) RLockEntry(key string) (func(), error) {
+	if err := os.MkdirAll(m.cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return rlockPath(m.GetTemplateCachePath(key))
+}
+
+// isEntryLock reports whether a file in the cache directory is the lock file of a cache entry
+func isEntryLock(name string) bool {
+	return strings.HasSuffix(name, lockFileSuffix) && name != CacheMetadataFile+lockFileSuffix
+}
+
+// MarkIncomplete flags a cache entry that is about to be changed in place.
+// The entry is not trusted until MarkComplete is called, so an update interrupted
+// halfway leaves an entry that is downloaded again rather than used.
+func MarkIncomplete(dir string) error {
+	if err := os.WriteFile(filepath.Join(dir, ".git", incompleteMarker), nil, 0644); err != nil {
+		return fmt.Errorf("failed to mark cache entry as incomplete: %w", err)
+	}
+	return nil
+}
+
+// MarkComplete clears the flag set by MarkIncomplete
+func MarkComplete(dir string) error {
+	if err := os.Remove(filepath.Join(dir, ".git", incompleteMarker)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to mark cache entry as complete: %w", err)
+	}
+	return nil
+}
+
+// isIncomplete reports whether a cache entry was left behind by an interrupted update
+func isIncomplete(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git", incompleteMarker))
+	return err == nil
+}
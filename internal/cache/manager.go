@@ -1,4 +1,4 @@
-// Package cache provides template caching functionality with TTL
+// Package cache provides template caching functionality with refresh policies
 package cache
 
 import (
@@ -6,16 +6,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/PickHD/pick-your-go/internal/config"
 )
 
 const (
-	// CacheTTL is the time-to-live for cache entries (24 hours)
-	CacheTTL = 24 * time.Hour
-	// CacheDirName is the name of the cache directory
+	// CacheDirName is the name of the cache directory under the user cache directory
 	CacheDirName = ".pick-your-go"
+	// EnvCacheDir overrides the cache directory
+	EnvCacheDir = "PICK_YOUR_GO_CACHE_DIR"
 	// CacheMetadataFile is the name of the cache metadata file
 	CacheMetadataFile = "cache-metadata.json"
 )
@@ -27,32 +28,102 @@ type CacheMetadata struct {
 
 // TemplateCacheInfo stores cache information for a template
 type TemplateCacheInfo struct {
+	Template    string    `json:"template"`
+	Source      Source    `json:"source"`
 	CachedAt    time.Time `json:"cached_at"`
 	LastChecked time.Time `json:"last_checked"`
+	LastUsed    time.Time `json:"last_used,omitempty"`
 	Path        string    `json:"path"`
-	Version     string    `json:"version,omitempty"`
+	Commit      string    `json:"commit,omitempty"`
+	// Version is the resolved ref (branch, tag or commit SHA) the template was downloaded at
+	Version string `json:"version,omitempty"`
+	// Archive is the compressed archive holding the template files, empty for expanded entries
+	Archive string `json:"archive,omitempty"`
 }
 
 // Manager handles template caching
 type Manager struct {
-	cacheDir string
-	metadata *CacheMetadata
+	cacheDir      string
+	metadata      *CacheMetadata
+	defaultPolicy config.RefreshPolicy
+	policies      map[string]config.RefreshPolicy
+	compression   string
+	// systemCaches are read-only caches looked up together with this cache
+	systemCaches []*Manager
+	// readOnly is set for system caches, which are never written
+	readOnly bool
 }
 
-// NewManager creates a new cache manager
+// cacheDirOverride is the cache directory given on the command line
+var cacheDirOverride string
+
+// SetCacheDirOverride sets the cache directory for all cache managers created afterwards (--cache-dir)
+func SetCacheDirOverride(dir string) {
+	cacheDirOverride = dir
+}
+
+// NewManager creates a new cache manager.
+// The cache directory is taken from --cache-dir, PICK_YOUR_GO_CACHE_DIR or the cache_dir
+// setting, in that order, and defaults to .pick-your-go under the user cache directory.
+// Entries are also looked up in the read-only system caches, see SystemCaches.
 func NewManager() *Manager {
-	cacheDir, _ := os.UserCacheDir()
-	cacheDir = filepath.Join(cacheDir, CacheDirName)
+	// Broken settings are reported where the settings are loaded for hooks,
+	// the cache falls back to its defaults
+	settings, err := config.LoadSettings()
+	if err != nil {
+		settings = &config.Settings{}
+	}
+
+	cacheDir := cacheDirOverride
+	if cacheDir == "" {
+		cacheDir = os.Getenv(EnvCacheDir)
+	}
+	if cacheDir == "" {
+		cacheDir = settings.CacheDir
+	}
+	if cacheDir == "" {
+		userCacheDir, _ := os.UserCacheDir()
+		cacheDir = filepath.Join(userCacheDir, CacheDirName)
+	}
+	if expanded, err := config.ExpandHome(cacheDir); err == nil {
+		cacheDir = expanded
+	}
 
 	// Ensure cache directory exists
 	os.MkdirAll(cacheDir, 0755)
 
-	return &Manager{
+	defaultPolicy, err := config.ParseRefreshPolicy(settings.Refresh)
+	if err != nil {
+		defaultPolicy = config.DefaultRefreshPolicy
+	}
+
+	m := &Manager{
 		cacheDir: cacheDir,
 		metadata: &CacheMetadata{
 			Templates: make(map[string]TemplateCacheInfo),
 		},
+		defaultPolicy: defaultPolicy,
+		policies:      make(map[string]config.RefreshPolicy),
+		compression:   settings.CacheCompression,
+	}
+	for _, dir := range systemCacheDirs(settings, cacheDir) {
+		m.systemCaches = append(m.systemCaches, newSystemCache(dir, m))
+	}
+
+	return m
+}
+
+// SetRefreshPolicy sets the refresh policy of a template, overriding the default policy
+func (m *Manager) SetRefreshPolicy(template string, policy config.RefreshPolicy) {
+	m.policies[template] = policy
+}
+
+// GetRefreshPolicy returns the refresh policy of a template
+func (m *Manager) GetRefreshPolicy(template string) config.RefreshPolicy {
+	if policy, ok := m.policies[template]; ok {
+		return policy
 	}
+	return m.defaultPolicy
 }
 
 // GetCacheDir returns the cache directory path
@@ -61,66 +132,142 @@ func (m *Manager) GetCacheDir() string {
 }
 
 // GetTemplateCachePath returns the cache path for a specific template
-func (m *Manager) GetTemplateCachePath(archType config.ArchitectureType) string {
-	return filepath.Join(m.cacheDir, string(archType))
+func (m *Manager) GetTemplateCachePath(key string) string {
+	return filepath.Join(m.cacheDir, key)
+}
+
+// Exists checks if a template is cached in the user or a system cache, regardless of its refresh policy
+func (m *Manager) Exists(key string) bool {
+	return m.entryCache(key) != nil
 }
 
-// IsCached checks if a template is cached and still valid
-func (m *Manager) IsCached(archType config.ArchitectureType) bool {
+// exists checks if a template is cached in this cache
+func (m *Manager) exists(key string) bool {
 	// Load metadata
 	if err := m.loadMetadata(); err != nil {
 		return false
 	}
 
-	info, exists := m.metadata.Templates[string(archType)]
+	info, exists := m.metadata.Templates[key]
 	if !exists {
 		return false
 	}
 
-	// Check if cache is still valid (within TTL)
-	return time.Since(info.CachedAt) < CacheTTL
+	if m.archived(key, &info) {
+		_, err := os.Stat(m.archivePath(info.Archive))
+		return err == nil
+	}
+
+	cachePath := m.GetTemplateCachePath(key)
+	stat, err := os.Stat(cachePath)
+	return err == nil && stat.IsDir() && !isIncomplete(cachePath)
 }
 
-// IsCacheExpired checks if the cache for a template has expired
-func (m *Manager) IsCacheExpired(archType config.ArchitectureType) bool {
-	if err := m.loadMetadata(); err != nil {
+// IsCached checks if a template is cached and does not need to be checked for updates
+// according to its refresh policy
+func (m *Manager) IsCached(key string) bool {
+	return m.Exists(key) && !m.IsCacheExpired(key)
+}
+
+// IsCacheExpired checks if a template was last confirmed up to date longer ago than
+// its refresh policy allows
+func (m *Manager) IsCacheExpired(key string) bool {
+	info, err := m.GetCacheInfo(key)
+	if err != nil {
 		return true
 	}
 
-	info, exists := m.metadata.Templates[string(archType)]
-	if !exists {
-		return true
+	lastChecked := info.LastChecked
+	if lastChecked.IsZero() {
+		lastChecked = info.CachedAt
 	}
 
-	return time.Since(info.CachedAt) >= CacheTTL
+	return m.GetRefreshPolicy(info.Template).Expired(lastChecked)
 }
 
-// UpdateCacheTime updates the cache time for a template
-func (m *Manager) UpdateCacheTime(archType config.ArchitectureType) error {
-	if err := m.loadMetadata(); err != nil {
-		return fmt.Errorf("failed to load metadata: %w", err)
-	}
+// UpdateCacheTime updates the cache time for a template, keeping the recorded source and commit
+func (m *Manager) UpdateCacheTime(key string) error {
+	return m.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		info := templates[key]
+		info.CachedAt = time.Now()
+		info.LastChecked = time.Now()
+		info.Path = m.GetTemplateCachePath(key)
+		templates[key] = info
+		return nil
+	})
+}
 
-	m.metadata.Templates[string(archType)] = TemplateCacheInfo{
-		CachedAt:    time.Now(),
-		LastChecked: time.Now(),
-		Path:        m.GetTemplateCachePath(archType),
+// UpdateCheckTime records that a cached template was confirmed to match its remote.
+// Entries read from a system cache are not recorded.
+func (m *Manager) UpdateCheckTime(key string) error {
+	if m.lookupCache(key) != m {
+		return nil
 	}
+	return m.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		info, exists := templates[key]
+		if !exists {
+			return fmt.Errorf("template not cached")
+		}
+		info.LastChecked = time.Now()
+		templates[key] = info
+		return nil
+	})
+}
 
-	if err := m.saveMetadata(); err != nil {
-		return fmt.Errorf("failed to save metadata: %w", err)
+// MarkUsed records that a cached template was used, pruning removes the least recently used entries first.
+// Entries read from a system cache are not recorded.
+func (m *Manager) MarkUsed(key string) error {
+	if m.lookupCache(key) != m {
+		return nil
 	}
+	return m.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		info, exists := templates[key]
+		if !exists {
+			return fmt.Errorf("template not cached")
+		}
+		info.LastUsed = time.Now()
+		templates[key] = info
+		return nil
+	})
+}
 
-	return nil
+// UpdateCacheSource records a freshly downloaded template: the template it belongs to,
+// the source it was cloned from and the commit it was cloned at
+func (m *Manager) UpdateCacheSource(template string, source Source, commit string) error {
+	key := source.Key()
+	return m.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		archive := templates[key].Archive
+		templates[key] = TemplateCacheInfo{
+			Template:    template,
+			Source:      source,
+			CachedAt:    time.Now(),
+			LastChecked: time.Now(),
+			LastUsed:    templates[key].LastUsed,
+			Path:        m.GetTemplateCachePath(key),
+			Version:     source.Ref,
+			Commit:      commit,
+		}
+
+		// The entry was downloaded again, its previous archive is outdated
+		if archive != "" && !archiveReferenced(templates, archive) {
+			return m.removeArchive(archive)
+		}
+		return nil
+	})
+}
+
+// GetCacheInfo returns cache information for a template, from the cache lookups read it from
+func (m *Manager) GetCacheInfo(key string) (*TemplateCacheInfo, error) {
+	return m.lookupCache(key).cacheInfo(key)
 }
 
-// GetCacheInfo returns cache information for a template
-func (m *Manager) GetCacheInfo(archType config.ArchitectureType) (*TemplateCacheInfo, error) {
+// cacheInfo returns cache information for a template in this cache
+func (m *Manager) cacheInfo(key string) (*TemplateCacheInfo, error) {
 	if err := m.loadMetadata(); err != nil {
 		return nil, fmt.Errorf("failed to load metadata: %w", err)
 	}
 
-	info, exists := m.metadata.Templates[string(archType)]
+	info, exists := m.metadata.Templates[key]
 	if !exists {
 		return nil, fmt.Errorf("template not cached")
 	}
@@ -128,8 +275,27 @@ func (m *Manager) GetCacheInfo(archType config.ArchitectureType) (*TemplateCache
 	return &info, nil
 }
 
+// GetEntries returns the cache information of all entries of this cache, keyed by cache key.
+// System cache entries are listed by LookupEntries.
+func (m *Manager) GetEntries() (map[string]TemplateCacheInfo, error) {
+	if err := m.loadMetadata(); err != nil {
+		return nil, fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	entries := make(map[string]TemplateCacheInfo, len(m.metadata.Templates))
+	for key, info := range m.metadata.Templates {
+		entries[key] = info
+	}
+
+	return entries, nil
+}
+
 // ClearCache removes all cached templates
 func (m *Manager) ClearCache() error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+
 	// Remove all subdirectories in cache dir
 	entries, err := os.ReadDir(m.cacheDir)
 	if err != nil {
@@ -142,21 +308,25 @@ func (m *Manager) ClearCache() error {
 			if err := os.RemoveAll(path); err != nil {
 				return fmt.Errorf("failed to remove %s: %w", path, err)
 			}
+		} else if isEntryLock(entry.Name()) {
+			removeUnusedLock(m.GetTemplateCachePath(strings.TrimSuffix(entry.Name(), lockFileSuffix)))
 		}
 	}
 
 	// Clear metadata
-	m.metadata.Templates = make(map[string]TemplateCacheInfo)
-	if err := m.saveMetadata(); err != nil {
-		return fmt.Errorf("failed to save metadata: %w", err)
-	}
-
-	return nil
+	return m.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		clear(templates)
+		return nil
+	})
 }
 
 // ClearTemplateCache removes cache for a specific template
-func (m *Manager) ClearTemplateCache(archType config.ArchitectureType) error {
-	cachePath := m.GetTemplateCachePath(archType)
+func (m *Manager) ClearTemplateCache(key string) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+
+	cachePath := m.GetTemplateCachePath(key)
 
 	// Remove template cache directory
 	if _, err := os.Stat(cachePath); err == nil {
@@ -166,11 +336,64 @@ func (m *Manager) ClearTemplateCache(archType config.ArchitectureType) error {
 	}
 
 	// Remove from metadata
+	err := m.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		archive := templates[key].Archive
+		delete(templates, key)
+
+		// Archives are shared by entries with the same content
+		if archive != "" && !archiveReferenced(templates, archive) {
+			return m.removeArchive(archive)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// A lock held by a download in progress stays, RemoveEntry removes it under the lock
+	removeUnusedLock(cachePath)
+	return nil
+}
+
+// RemoveEntry removes a cache entry like ClearTemplateCache, waiting for a download
+// of the entry in progress in another process to finish first
+func (m *Manager) RemoveEntry(key string) error {
+	unlock, err := m.LockEntry(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := m.ClearTemplateCache(key); err != nil {
+		return err
+	}
+	removeLock(m.GetTemplateCachePath(key))
+	return nil
+}
+
+// updateMetadata applies a change to the cache metadata while holding the metadata lock,
+// so that changes made by concurrent processes are not lost
+func (m *Manager) updateMetadata(change func(templates map[string]TemplateCacheInfo) error) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.cacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	unlock, err := lockPath(filepath.Join(m.cacheDir, CacheMetadataFile))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if err := m.loadMetadata(); err != nil {
 		return fmt.Errorf("failed to load metadata: %w", err)
 	}
 
-	delete(m.metadata.Templates, string(archType))
+	if err := change(m.metadata.Templates); err != nil {
+		return err
+	}
 
 	if err := m.saveMetadata(); err != nil {
 		return fmt.Errorf("failed to save metadata: %w", err)
@@ -193,6 +416,8 @@ func (m *Manager) loadMetadata() error {
 		return fmt.Errorf("failed to read metadata file: %w", err)
 	}
 
+	// Entries removed by another process must not survive from an earlier load
+	m.metadata.Templates = make(map[string]TemplateCacheInfo)
 	if err := json.Unmarshal(data, m.metadata); err != nil {
 		return fmt.Errorf("failed to parse metadata: %w", err)
 	}
@@ -200,7 +425,9 @@ func (m *Manager) loadMetadata() error {
 	return nil
 }
 
-// saveMetadata saves cache metadata to disk
+// saveMetadata saves cache metadata to disk.
+// The file is written next to the metadata file and renamed over it, so that readers
+// never see a partly written file.
 func (m *Manager) saveMetadata() error {
 	metadataPath := filepath.Join(m.cacheDir, CacheMetadataFile)
 
@@ -209,18 +436,54 @@ func (m *Manager) saveMetadata() error {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	if err := os.WriteFile(metadataPath, data, 0644); err != nil {
+	tempFile, err := os.CreateTemp(m.cacheDir, "."+CacheMetadataFile+"-*")
+	if err != nil {
 		return fmt.Errorf("failed to write metadata file: %w", err)
 	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	if err := tempFile.Chmod(0644); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), metadataPath); err != nil {
+		return fmt.Errorf("failed to replace metadata file: %w", err)
+	}
 
 	return nil
 }
 
 // GetCacheSize returns the size of the cache directory in bytes
 func (m *Manager) GetCacheSize() (int64, error) {
+	return dirSize(m.cacheDir)
+}
+
+// GetEntrySize returns the size of a cache entry in bytes
+func (m *Manager) GetEntrySize(key string) (int64, error) {
+	if info, err := m.cacheInfo(key); err == nil && m.archived(key, info) {
+		stat, err := os.Stat(m.archivePath(info.Archive))
+		if err != nil {
+			return 0, err
+		}
+		return stat.Size(), nil
+	}
+
+	return dirSize(m.GetTemplateCachePath(key))
+}
+
+// dirSize returns the size of the files in a directory tree in bytes
+func dirSize(dir string) (int64, error) {
 	var size int64
 
-	err := filepath.Walk(m.cacheDir, func(_ string, info os.FileInfo, err error) error {
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -234,8 +497,8 @@ func (m *Manager) GetCacheSize() (int64, error) {
 }
 
 // GetCacheAge returns the age of the cache for a template
-func (m *Manager) GetCacheAge(archType config.ArchitectureType) (time.Duration, error) {
-	info, err := m.GetCacheInfo(archType)
+func (m *Manager) GetCacheAge(key string) (time.Duration, error) {
+	info, err := m.GetCacheInfo(key)
 	if err != nil {
 		return 0, err
 	}
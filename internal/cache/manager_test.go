@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// newTestManager returns a cache manager on an empty cache directory
+func newTestManager(t *testing.T, cacheDir string) *Manager {
+	t.Helper()
+	t.Setenv(EnvCacheDir, cacheDir)
+	t.Setenv(EnvSystemCacheDirs, "")
+	t.Setenv(config.EnvConfigDir, t.TempDir())
+	return NewManager()
+}
+
+// TestConcurrentMetadataUpdates tests that concurrent writers do not lose each other's entries
+func TestConcurrentMetadataUpdates(t *testing.T) {
+	cacheDir := t.TempDir()
+	newTestManager(t, cacheDir)
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate managers behave like separate processes, each keeps its own metadata copy
+			manager := NewManager()
+			source := Source{Repository: fmt.Sprintf("https://github.com/example/t%d", i), Ref: "main"}
+			errs <- manager.UpdateCacheSource(fmt.Sprintf("t%d", i), source, "abc1234")
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("UpdateCacheSource failed: %v", err)
+		}
+	}
+
+	entries, err := NewManager().GetEntries()
+	if err != nil {
+		t.Fatalf("GetEntries failed: %v", err)
+	}
+	if len(entries) != writers {
+		t.Errorf("expected %d entries, got %d", writers, len(entries))
+	}
+
+	// Only the metadata file and its lock are left behind
+	leftovers, _ := filepath.Glob(filepath.Join(cacheDir, "."+CacheMetadataFile+"-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("expected no temporary metadata files, got %v", leftovers)
+	}
+}
+
+// TestExistsIncompleteEntry tests that an entry interrupted during an in-place update is not trusted
+func TestExistsIncompleteEntry(t *testing.T) {
+	manager := newTestManager(t, t.TempDir())
+
+	source := Source{Repository: "https://github.com/example/demo", Ref: "main"}
+	key := source.Key()
+	cachePath := manager.GetTemplateCachePath(key)
+	if err := os.MkdirAll(filepath.Join(cachePath, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.UpdateCacheSource("demo", source, "abc1234"); err != nil {
+		t.Fatalf("UpdateCacheSource failed: %v", err)
+	}
+	if info, err := manager.GetCacheInfo(key); err != nil || info.Version != source.Ref {
+		t.Errorf("expected version %s to be recorded, got %+v (%v)", source.Ref, info, err)
+	}
+
+	if !manager.Exists(key) {
+		t.Fatalf("expected the entry to exist")
+	}
+
+	if err := MarkIncomplete(cachePath); err != nil {
+		t.Fatalf("MarkIncomplete failed: %v", err)
+	}
+	if manager.Exists(key) {
+		t.Errorf("expected an incomplete entry not to count as cached")
+	}
+
+	if err := MarkComplete(cachePath); err != nil {
+		t.Fatalf("MarkComplete failed: %v", err)
+	}
+	if !manager.Exists(key) {
+		t.Errorf("expected the entry to exist once complete")
+	}
+}
+
+// addTestEntry caches a fake template of the given size that was last used age ago
+func addTestEntry(t *testing.T, manager *Manager, name string, size int, age time.Duration) string {
+	t.Helper()
+	source := Source{Repository: "https://github.com/example/" + name, Ref: "main"}
+	key := source.Key()
+
+	cachePath := manager.GetTemplateCachePath(key)
+	if err := os.MkdirAll(cachePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cachePath, "data"), make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.UpdateCacheSource(name, source, "abc1234"); err != nil {
+		t.Fatalf("UpdateCacheSource failed: %v", err)
+	}
+
+	err := manager.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		info := templates[key]
+		info.LastUsed = time.Now().Add(-age)
+		templates[key] = info
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// TestPrune tests removing entries by age and least recently used entries by size
+func TestPrune(t *testing.T) {
+	manager := newTestManager(t, t.TempDir())
+
+	old := addTestEntry(t, manager, "old", 100, 40*24*time.Hour)
+	stale := addTestEntry(t, manager, "stale", 100, 10*24*time.Hour)
+	recent := addTestEntry(t, manager, "recent", 100, time.Hour)
+	orphan := manager.GetTemplateCachePath("layered")
+	if err := os.MkdirAll(orphan, 0755); err != nil {
+		t.Fatal(err)
+	}
+	orphanLock := manager.GetTemplateCachePath("gone") + lockFileSuffix
+	if err := os.WriteFile(orphanLock, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := manager.Prune(PruneOptions{OlderThan: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].Key != old || pruned[0].Size != 100 {
+		t.Errorf("expected only the old entry to be pruned, got %+v", pruned)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("expected the orphaned directory to be removed")
+	}
+	for _, lockFile := range []string{manager.GetTemplateCachePath(old) + lockFileSuffix, orphan + lockFileSuffix, orphanLock} {
+		if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", filepath.Base(lockFile))
+		}
+	}
+
+	pruned, err = manager.Prune(PruneOptions{MaxSize: 150})
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].Key != stale {
+		t.Errorf("expected the least recently used entry to be pruned, got %+v", pruned)
+	}
+	if !manager.Exists(recent) {
+		t.Errorf("expected the recent entry to be kept")
+	}
+}
+
+// TestLockEntryRemoved tests that a process waiting on a lock file removed with its entry
+// does not share the lock with a process that locked the new lock file
+func TestLockEntryRemoved(t *testing.T) {
+	manager := newTestManager(t, t.TempDir())
+	key := Source{Repository: "https://github.com/example/demo", Ref: "main"}.Key()
+
+	unlockFirst, err := manager.LockEntry(key)
+	if err != nil {
+		t.Fatalf("LockEntry failed: %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		unlock, err := manager.LockEntry(key)
+		if err != nil {
+			t.Errorf("LockEntry failed: %v", err)
+			unlock = func() {}
+		}
+		acquired <- unlock
+	}()
+	// Let the waiter open the lock file before it is removed
+	time.Sleep(50 * time.Millisecond)
+
+	removeLock(manager.GetTemplateCachePath(key))
+	unlockNew, err := manager.LockEntry(key)
+	if err != nil {
+		t.Fatalf("LockEntry failed: %v", err)
+	}
+	unlockFirst()
+
+	select {
+	case unlock := <-acquired:
+		unlock()
+		t.Fatalf("expected the waiter to wait for the lock on the new file")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlockNew()
+	(<-acquired)()
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PruneOptions selects the cache entries removed by Prune
+type PruneOptions struct {
+	// OlderThan removes entries not used for longer than this, zero keeps them
+	OlderThan time.Duration
+	// MaxSize removes the least recently used entries until the cache fits, zero means no limit
+	MaxSize int64
+}
+
+// PrunedEntry describes a cache entry removed by Prune
+type PrunedEntry struct {
+	Key  string
+	Info TemplateCacheInfo
+	// Size is the space freed, zero when the archive of the entry is still used by another entry
+	Size int64
+}
+
+// LastUsedAt returns when a cache entry was last used.
+// Entries cached before uses were recorded count as used when they were cached.
+func (info TemplateCacheInfo) LastUsedAt() time.Time {
+	if info.LastUsed.IsZero() {
+		return info.CachedAt
+	}
+	return info.LastUsed
+}
+
+// Prune removes cache entries that were not used for longer than OlderThan, then the least
+// recently used entries until the cache holds at most MaxSize bytes. Directories in the cache
+// that no metadata entry refers to, e.g. left behind by older versions, are removed as well.
+func (m *Manager) Prune(opts PruneOptions) ([]PrunedEntry, error) {
+	if err := m.removeOrphans(); err != nil {
+		return nil, err
+	}
+
+	entries, err := m.GetEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries with the same files share one archive, which is counted once
+	// and only freed together with the last entry stored in it
+	archiveRefs := make(map[string]int)
+	candidates := make([]PrunedEntry, 0, len(entries))
+	var total int64
+	for key, info := range entries {
+		size, err := m.GetEntrySize(key)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to measure cache entry %s: %w", key, err)
+		}
+		candidates = append(candidates, PrunedEntry{Key: key, Info: info, Size: size})
+
+		if m.archived(key, &info) {
+			archiveRefs[info.Archive]++
+			if archiveRefs[info.Archive] > 1 {
+				continue
+			}
+		}
+		total += size
+	}
+
+	// Least recently used first
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Info.LastUsedAt().Before(candidates[j].Info.LastUsedAt())
+	})
+
+	var pruned []PrunedEntry
+	for _, candidate := range candidates {
+		expired := opts.OlderThan > 0 && time.Since(candidate.Info.LastUsedAt()) > opts.OlderThan
+		oversized := opts.MaxSize > 0 && total > opts.MaxSize
+		if !expired && !oversized {
+			continue
+		}
+
+		if err := m.RemoveEntry(candidate.Key); err != nil {
+			return pruned, err
+		}
+		if archive := candidate.Info.Archive; archiveRefs[archive] > 0 {
+			archiveRefs[archive]--
+			if archiveRefs[archive] > 0 {
+				// The archive is still used by another entry
+				candidate.Size = 0
+			}
+		}
+		pruned = append(pruned, candidate)
+		total -= candidate.Size
+	}
+
+	return pruned, nil
+}
+
+// removeOrphans removes cache directories without a metadata entry.
+// Hidden directories are temporary clones of downloads in progress and are left alone.
+func (m *Manager) removeOrphans() error {
+	entries, err := m.GetEntries()
+	if err != nil {
+		return err
+	}
+
+	dirs, err := os.ReadDir(m.cacheDir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	for _, dir := range dirs {
+		key := dir.Name()
+		// Lock files of entries that are gone, e.g. removed by older versions
+		if name, ok := strings.CutSuffix(key, lockFileSuffix); ok && isEntryLock(key) {
+			if _, known := entries[name]; !known {
+				removeUnusedLock(m.GetTemplateCachePath(name))
+			}
+			continue
+		}
+		if !dir.IsDir() || strings.HasPrefix(key, ".") || key == ArchivesDirName {
+			continue
+		}
+		if _, known := entries[key]; known {
+			continue
+		}
+
+		// A download holds the entry lock until its metadata is written
+		if err := m.removeOrphan(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeOrphan removes a cache directory under its entry lock, unless a download
+// recorded it in the metadata in the meantime
+func (m *Manager) removeOrphan(key string) error {
+	unlock, err := m.LockEntry(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := m.cacheInfo(key); err == nil {
+		return nil
+	}
+
+	if err := os.RemoveAll(m.GetTemplateCachePath(key)); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", m.GetTemplateCachePath(key), err)
+	}
+	removeLock(m.GetTemplateCachePath(key))
+	return nil
+}
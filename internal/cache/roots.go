@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+const (
+	// EnvSystemCacheDirs lists the read-only system caches, separated like PATH entries.
+	// Set to an empty value, no system cache is used.
+	EnvSystemCacheDirs = "PICK_YOUR_GO_SYSTEM_CACHE_DIRS"
+	// DefaultSystemCacheDir is the system cache used when none is configured
+	DefaultSystemCacheDir = "/opt/pick-your-go/cache"
+)
+
+// systemCacheDirs returns the system cache directories from PICK_YOUR_GO_SYSTEM_CACHE_DIRS
+// or the system_cache_dirs setting, without the user cache directory itself
+func systemCacheDirs(settings *config.Settings, cacheDir string) []string {
+	dirs := []string{DefaultSystemCacheDir}
+	if value, ok := os.LookupEnv(EnvSystemCacheDirs); ok {
+		dirs = filepath.SplitList(value)
+	} else if settings.SystemCacheDirs != nil {
+		dirs = settings.SystemCacheDirs
+	}
+
+	var systemDirs []string
+	for _, dir := range dirs {
+		if expanded, err := config.ExpandHome(dir); err == nil {
+			dir = expanded
+		}
+		// Admins populate a system cache by using it as their cache directory
+		if dir == "" || filepath.Clean(dir) == filepath.Clean(cacheDir) {
+			continue
+		}
+		systemDirs = append(systemDirs, dir)
+	}
+	return systemDirs
+}
+
+// newSystemCache returns a read-only cache manager of a system cache directory
+func newSystemCache(dir string, m *Manager) *Manager {
+	return &Manager{
+		cacheDir: dir,
+		metadata: &CacheMetadata{
+			Templates: make(map[string]TemplateCacheInfo),
+		},
+		defaultPolicy: m.defaultPolicy,
+		policies:      m.policies,
+		readOnly:      true,
+	}
+}
+
+// SystemCaches returns the read-only system caches looked up before the user cache
+func (m *Manager) SystemCaches() []*Manager {
+	return m.systemCaches
+}
+
+// IsReadOnly reports whether the cache is a read-only system cache
+func (m *Manager) IsReadOnly() bool {
+	return m.readOnly
+}
+
+// checkWritable fails for read-only system caches
+func (m *Manager) checkWritable() error {
+	if m.readOnly {
+		return fmt.Errorf("cache %s is read-only", m.cacheDir)
+	}
+	return nil
+}
+
+// roots returns the system caches followed by the user cache
+func (m *Manager) roots() []*Manager {
+	return append(append([]*Manager{}, m.systemCaches...), m)
+}
+
+// entryCache returns the cache holding the freshest valid copy of an entry, the user cache
+// winning ties. It returns nil when no cache has a valid copy.
+func (m *Manager) entryCache(key string) *Manager {
+	var found *Manager
+	var newest time.Time
+	for _, root := range m.roots() {
+		if !root.exists(key) {
+			continue
+		}
+		info, err := root.cacheInfo(key)
+		if err != nil {
+			continue
+		}
+		if found == nil || !info.CachedAt.Before(newest) {
+			found, newest = root, info.CachedAt
+		}
+	}
+	return found
+}
+
+// lookupCache returns the cache an entry is read from: the cache holding its freshest
+// valid copy, or the user cache when no cache has one
+func (m *Manager) lookupCache(key string) *Manager {
+	if root := m.entryCache(key); root != nil {
+		return root
+	}
+	return m
+}
+
+// LookupEntries returns the cache information of the entries of the user and system caches
+// as lookups find them, keyed by cache key
+func (m *Manager) LookupEntries() (map[string]TemplateCacheInfo, error) {
+	keys := make(map[string]bool)
+	for _, root := range m.roots() {
+		entries, err := root.GetEntries()
+		if err != nil {
+			if root == m {
+				return nil, err
+			}
+			fmt.Printf("Warning: failed to read system cache %s: %v\n", root.cacheDir, err)
+			continue
+		}
+		for key := range entries {
+			keys[key] = true
+		}
+	}
+
+	entries := make(map[string]TemplateCacheInfo, len(keys))
+	for key := range keys {
+		if info, err := m.GetCacheInfo(key); err == nil {
+			entries[key] = *info
+		}
+	}
+
+	return entries, nil
+}
+
+// EntryClone returns the git clone of a cache entry as found by lookups.
+// Archived entries keep no git history and have no clone.
+func (m *Manager) EntryClone(key string) (string, bool) {
+	root := m.lookupCache(key)
+	info, err := root.cacheInfo(key)
+	if err != nil || root.archived(key, info) {
+		return "", false
+	}
+
+	dir := root.GetTemplateCachePath(key)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return "", false
+	}
+	return dir, true
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSystemCacheLookup tests that lookups take the freshest copy of an entry and writes stay in the user cache
+func TestSystemCacheLookup(t *testing.T) {
+	files := map[string]string{"main.go": "package main\n", ".git/HEAD": "ref: refs/heads/main\n"}
+	systemDir := t.TempDir()
+	admin := newTestManager(t, systemDir)
+	source := Source{Repository: "https://github.com/example/demo", Ref: "main"}
+	key := writeTestEntry(t, admin, source, files)
+
+	userDir := t.TempDir()
+	t.Setenv(EnvCacheDir, userDir)
+	t.Setenv(EnvSystemCacheDirs, systemDir)
+	manager := NewManager()
+
+	if len(manager.SystemCaches()) != 1 || !manager.SystemCaches()[0].IsReadOnly() {
+		t.Fatalf("expected one read-only system cache, got %d", len(manager.SystemCaches()))
+	}
+	if !manager.Exists(key) {
+		t.Fatalf("expected the system cache entry to be found")
+	}
+	if dir, _, _ := manager.EntryPath(key); dir != admin.GetTemplateCachePath(key) {
+		t.Errorf("expected the system copy, got %s", dir)
+	}
+	if err := manager.MarkUsed(key); err != nil {
+		t.Errorf("MarkUsed failed: %v", err)
+	}
+	if entries, _ := manager.GetEntries(); len(entries) != 0 {
+		t.Errorf("expected nothing to be written to the user cache, got %v", entries)
+	}
+	if err := manager.SystemCaches()[0].UpdateCacheSource("demo", source, "abc1234"); err == nil {
+		t.Errorf("expected the system cache to refuse writes")
+	}
+
+	// A fresher download in the user cache wins
+	writeTestEntry(t, manager, source, files)
+	if dir, _, _ := manager.EntryPath(key); dir != manager.GetTemplateCachePath(key) {
+		t.Errorf("expected the fresher user copy, got %s", dir)
+	}
+	if entries, _ := manager.LookupEntries(); len(entries) != 1 {
+		t.Errorf("expected one entry across the caches, got %d", len(entries))
+	}
+
+	// Until the system copy is refreshed
+	err := admin.updateMetadata(func(templates map[string]TemplateCacheInfo) error {
+		info := templates[key]
+		info.CachedAt = time.Now().Add(time.Hour)
+		templates[key] = info
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir, _, _ := manager.EntryPath(key); dir != admin.GetTemplateCachePath(key) {
+		t.Errorf("expected the fresher system copy, got %s", dir)
+	}
+}
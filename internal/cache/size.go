@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the size units accepted by ParseSize, in powers of 1024
+var sizeUnits = []string{"B", "KB", "MB", "GB", "TB"}
+
+// ParseSize parses a size such as 500MB or 2GB into bytes. Units are powers of 1024,
+// the B may be left out (500M) and a plain number is a number of bytes.
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+
+	number, multiplier := s, int64(1)
+	for i := len(sizeUnits) - 1; i >= 0; i-- {
+		unit := sizeUnits[i]
+		if rest, ok := strings.CutSuffix(s, unit); ok {
+			number, multiplier = rest, int64(1)<<(10*i)
+			break
+		}
+		if i > 0 {
+			if rest, ok := strings.CutSuffix(s, unit[:1]); ok {
+				number, multiplier = rest, int64(1)<<(10*i)
+				break
+			}
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected e.g. 500MB or 2GB", value)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize formats a number of bytes for display, e.g. 12.3 MB
+func FormatSize(bytes int64) string {
+	size := float64(bytes)
+	unit := 0
+	for size >= 1024 && unit < len(sizeUnits)-1 {
+		size /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	return fmt.Sprintf("%.1f %s", size, sizeUnits[unit])
+}
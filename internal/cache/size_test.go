@@ -0,0 +1,55 @@
+package cache
+
+import "testing"
+
+// TestParseSize tests parsing sizes with and without units
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+		wantErr  bool
+	}{
+		{"500MB", 500 << 20, false},
+		{"500mb", 500 << 20, false},
+		{"2GB", 2 << 30, false},
+		{"1.5G", 3 << 29, false},
+		{"10KB", 10 << 10, false},
+		{"100B", 100, false},
+		{"4096", 4096, false},
+		{"", 0, true},
+		{"MB", 0, true},
+		{"-1GB", 0, true},
+		{"lots", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseSize(%q) = %d, expected %d", tt.value, got, tt.expected)
+		}
+	}
+}
+
+// TestFormatSize tests formatting sizes for display
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{500 << 20, "500.0 MB"},
+		{3 << 30, "3.0 GB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSize(tt.bytes); got != tt.expected {
+			t.Errorf("FormatSize(%d) = %q, expected %q", tt.bytes, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Source identifies the template files a cache entry holds
+type Source struct {
+	// Repository is the template repository URL
+	Repository string `json:"repository"`
+	// Ref is the branch, tag or commit SHA the entry was cloned at
+	Ref string `json:"ref"`
+	// Subdir is the template directory inside the repository, empty for the repository root
+	Subdir string `json:"subdir,omitempty"`
+}
+
+// Key returns the cache entry key of the source, a hash of repository, ref and subdirectory.
+// Different versions of the same template therefore live side by side.
+func (s Source) Key() string {
+	sum := sha256.Sum256([]byte(s.Repository + "\x00" + s.Ref + "\x00" + s.Subdir))
+	return hex.EncodeToString(sum[:])[:16]
+}
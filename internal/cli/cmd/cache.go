@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/PickHD/pick-your-go/internal/cache"
+	"github.com/PickHD/pick-your-go/internal/config"
+	"github.com/PickHD/pick-your-go/internal/template"
+
+	"github.com/spf13/cobra"
+)
+
+// CacheCommand represents the cache command
+type CacheCommand struct {
+	cmd *cobra.Command
+}
+
+// NewCacheCommand creates a new cache command with subcommands
+func NewCacheCommand() *cobra.Command {
+	cacheCmd := &CacheCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and clean up the template cache",
+		Long:  `Show what the template cache holds, remove entries or prune unused ones.`,
+	}
+
+	// Add subcommands
+	cmd.AddCommand(cacheCmd.NewInfoCommand())
+	cmd.AddCommand(cacheCmd.NewClearCommand())
+	cmd.AddCommand(cacheCmd.NewPruneCommand())
+	cmd.AddCommand(cacheCmd.NewVerifyCommand())
+
+	cacheCmd.cmd = cmd
+	return cmd
+}
+
+// NewInfoCommand creates a new cache info command
+func (c *CacheCommand) NewInfoCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "info",
+		Short: "Show the cached templates",
+		Long:  `Show the size, age, commit and source of every cached template, in the user cache and the read-only system caches.`,
+		Args:  cobra.NoArgs,
+		RunE:  c.runInfo,
+	}
+}
+
+// runInfo executes the info command
+func (c *CacheCommand) runInfo(cmd *cobra.Command, args []string) error {
+	cacheMgr := cache.NewManager()
+
+	entries, err := cacheMgr.GetEntries()
+	if err != nil {
+		return err
+	}
+	total, err := cacheMgr.GetCacheSize()
+	if err != nil {
+		return fmt.Errorf("failed to measure cache: %w", err)
+	}
+
+	fmt.Printf("\nCache directory: %s\n", cacheMgr.GetCacheDir())
+	fmt.Printf("Total size: %s\n", cache.FormatSize(total))
+	fmt.Printf("Entries: %d\n", len(entries))
+	printEntries(cacheMgr, entries)
+
+	// System caches are only listed when they hold templates
+	for _, systemMgr := range cacheMgr.SystemCaches() {
+		systemEntries, err := systemMgr.GetEntries()
+		if err != nil || len(systemEntries) == 0 {
+			continue
+		}
+
+		fmt.Printf("\nSystem cache (read-only): %s\n", systemMgr.GetCacheDir())
+		fmt.Printf("Entries: %d\n", len(systemEntries))
+		printEntries(systemMgr, systemEntries)
+	}
+
+	fmt.Println()
+
+	return nil
+}
+
+// printEntries prints the details of the entries of one cache
+func printEntries(cacheMgr *cache.Manager, entries map[string]cache.TemplateCacheInfo) {
+	for _, key := range sortedEntryKeys(entries) {
+		info := entries[key]
+
+		size := "unknown"
+		if bytes, err := cacheMgr.GetEntrySize(key); err == nil {
+			size = cache.FormatSize(bytes)
+		}
+
+		fmt.Printf("\n%s @ %s\n", info.Template, info.Source.Ref)
+		source := info.Source.Repository
+		if info.Source.Subdir != "" {
+			source += " (" + info.Source.Subdir + ")"
+		}
+		fmt.Printf("  Source:    %s\n", source)
+		if info.Commit != "" {
+			fmt.Printf("  Commit:    %s\n", info.Commit)
+		}
+		fmt.Printf("  Size:      %s\n", size)
+		fmt.Printf("  Cached:    %s ago\n", formatAge(time.Since(info.CachedAt)))
+		if !cacheMgr.IsReadOnly() {
+			fmt.Printf("  Last used: %s ago\n", formatAge(time.Since(info.LastUsedAt())))
+		}
+		if info.Archive != "" {
+			fmt.Printf("  Archive:   %s\n", filepath.Join(cacheMgr.GetCacheDir(), cache.ArchivesDirName, info.Archive))
+		} else {
+			fmt.Printf("  Path:      %s\n", cacheMgr.GetTemplateCachePath(key))
+		}
+	}
+}
+
+// sortedEntryKeys returns the keys of cache entries ordered by template and ref
+func sortedEntryKeys(entries map[string]cache.TemplateCacheInfo) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := entries[keys[i]], entries[keys[j]]
+		if a.Template != b.Template {
+			return a.Template < b.Template
+		}
+		return a.Source.Ref < b.Source.Ref
+	})
+	return keys
+}
+
+// formatAge formats a duration in the largest whole unit, e.g. 3d, 5h or 12m
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	default:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	}
+}
+
+// NewClearCommand creates a new cache clear command
+func (c *CacheCommand) NewClearCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear [template]",
+		Short: "Remove cached templates",
+		Long: `Remove every cached template, or all cached versions of one template.
+The template is named as in 'pick-your-go cache info', overlays as overlay-<name>.`,
+		Example: `  pick-your-go cache clear
+  pick-your-go cache clear layered
+  pick-your-go cache clear overlay-docker`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: c.runClear,
+	}
+}
+
+// runClear executes the clear command
+func (c *CacheCommand) runClear(cmd *cobra.Command, args []string) error {
+	manager := template.NewManager()
+
+	if len(args) == 0 {
+		if err := manager.ClearAllCache(); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+		fmt.Println("Template cache cleared")
+		return nil
+	}
+
+	removed, err := manager.ClearTemplateCache(config.ArchitectureType(args[0]))
+	if err != nil {
+		return fmt.Errorf("failed to clear %s: %w", args[0], err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("%s is not cached, see 'pick-your-go cache info'", args[0])
+	}
+
+	fmt.Printf("Removed %d cache entries of %s\n", removed, args[0])
+	return nil
+}
+
+// PruneCommand represents the cache prune command
+type PruneCommand struct {
+	cmd       *cobra.Command
+	olderThan string
+	maxSize   string
+}
+
+// NewPruneCommand creates a new cache prune command
+func (c *CacheCommand) NewPruneCommand() *cobra.Command {
+	pruneCmd := &PruneCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove unused cached templates",
+		Long: `Remove cached templates that were not used for a while, or the least recently
+used ones until the cache fits a size limit. Cache directories no entry refers to,
+e.g. left behind by older versions, are removed as well.`,
+		Example: `  pick-your-go cache prune --older-than 30d
+  pick-your-go cache prune --max-size 500MB
+  pick-your-go cache prune --older-than 90d --max-size 1GB`,
+		Args: cobra.NoArgs,
+		RunE: pruneCmd.Run,
+	}
+
+	cmd.Flags().StringVar(&pruneCmd.olderThan, "older-than", "", "Remove entries not used for this long (e.g. 30d, 12h)")
+	cmd.Flags().StringVar(&pruneCmd.maxSize, "max-size", "", "Remove least recently used entries until the cache fits (e.g. 500MB, 2GB)")
+
+	pruneCmd.cmd = cmd
+	return cmd
+}
+
+// Run executes the prune command
+func (c *PruneCommand) Run(cmd *cobra.Command, args []string) error {
+	if c.olderThan == "" && c.maxSize == "" {
+		return fmt.Errorf("nothing to prune, pass --older-than and/or --max-size")
+	}
+
+	var opts cache.PruneOptions
+	if c.olderThan != "" {
+		olderThan, err := config.ParseDuration(c.olderThan)
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+		opts.OlderThan = olderThan
+	}
+	if c.maxSize != "" {
+		maxSize, err := cache.ParseSize(c.maxSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-size: %w", err)
+		}
+		opts.MaxSize = maxSize
+	}
+
+	pruned, err := cache.NewManager().Prune(opts)
+	for _, entry := range pruned {
+		fmt.Printf("  Removed %s @ %s (%s, last used %s ago)\n", entry.Info.Template, entry.Info.Source.Ref,
+			cache.FormatSize(entry.Size), formatAge(time.Since(entry.Info.LastUsedAt())))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to prune cache: %w", err)
+	}
+
+	var freed int64
+	for _, entry := range pruned {
+		freed += entry.Size
+	}
+	fmt.Printf("Pruned %d cache entries, freed %s\n", len(pruned), cache.FormatSize(freed))
+
+	return nil
+}
+
+// NewVerifyCommand creates a new cache verify command
+func (c *CacheCommand) NewVerifyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check cached templates for modified, missing or extra files",
+		Long: `Compare the files of every cached template with the content hashes recorded
+when it was downloaded. Corrupted entries are downloaded again the next time they
+are used, or can be removed with 'pick-your-go cache clear'. Templates found in a
+system cache are checked there.`,
+		Args: cobra.NoArgs,
+		RunE: c.runVerify,
+	}
+}
+
+// runVerify executes the verify command
+func (c *CacheCommand) runVerify(cmd *cobra.Command, args []string) error {
+	cacheMgr := cache.NewManager()
+
+	// The copies lookups use, system caches included
+	entries, err := cacheMgr.LookupEntries()
+	if err != nil {
+		return err
+	}
+
+	corrupted := 0
+	for _, key := range sortedEntryKeys(entries) {
+		info := entries[key]
+
+		report, err := cacheMgr.VerifyEntry(key)
+		if err != nil {
+			return fmt.Errorf("failed to verify %s @ %s: %w", info.Template, info.Source.Ref, err)
+		}
+
+		fmt.Printf("%s @ %s: %s\n", info.Template, info.Source.Ref, report)
+		if report.OK() {
+			continue
+		}
+
+		corrupted++
+		for _, file := range report.Modified {
+			fmt.Printf("  modified: %s\n", file)
+		}
+		for _, file := range report.Missing {
+			fmt.Printf("  missing:  %s\n", file)
+		}
+		for _, file := range report.Extra {
+			fmt.Printf("  extra:    %s\n", file)
+		}
+	}
+
+	if corrupted > 0 {
+		return fmt.Errorf("%d of %d cache entries failed verification, they are downloaded again on their next use", corrupted, len(entries))
+	}
+
+	fmt.Printf("All %d cache entries verified\n", len(entries))
+	return nil
+}
@@ -3,18 +3,28 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/PickHD/pick-your-go/internal/config"
 	"github.com/PickHD/pick-your-go/internal/generator"
+	"github.com/PickHD/pick-your-go/internal/template"
 	"github.com/PickHD/pick-your-go/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
 // InitCommand represents the init command
 type InitCommand struct {
-	cmd      *cobra.Command
-	archType string
-	yes      bool // Skip confirmation
+	cmd         *cobra.Command
+	archType    string
+	templateDir string
+	version     string
+	overlays    []string
+	setValues   []string
+	valuesFile  string
+	noHooks     bool
+	trust       bool
+	offline     bool
+	yes         bool // Skip confirmation
 }
 
 // NewInitCommand creates a new init command
@@ -28,17 +38,37 @@ func NewInitCommand() *cobra.Command {
 This command will guide you through an interactive process to:
 1. Choose your architecture pattern (Layered, Modular, or Hexagonal)
 2. Provide project details (name, module path, author, description)
-3. Generate a complete project structure based on your selection`,
+3. Generate a complete project structure based on your selection
+
+Use --template-dir to generate from a template checked out on disk. The
+cache and the GitHub token are skipped entirely in that case.
+
+Use --template-version to pin the template to a tag (v1.4.0), a commit SHA
+or a semver range of tags (^1.2).
+
+Use --with to apply feature overlays (e.g. --with docker,ci) in order on top
+of the chosen template.
+
+Use --offline (or PICK_YOUR_GO_OFFLINE=1) to generate from cached templates
+regardless of their age without contacting any remote.`,
 		RunE: initCmd.Run,
 	}
 
 	// Add flags
-	cmd.Flags().StringVarP(&initCmd.archType, "architecture", "a", "", "Architecture type: layered, modular, or hexagonal")
+	cmd.Flags().StringVarP(&initCmd.archType, "architecture", "a", "", "Architecture type: layered, modular, hexagonal, or a type from the template registry")
 	cmd.Flags().StringP("name", "n", "", "Project name")
 	cmd.Flags().StringP("module", "m", "", "Go module path (e.g., github.com/user/project)")
 	cmd.Flags().StringP("output", "o", ".", "Output directory for the project")
 	cmd.Flags().StringP("author", "u", "", "Author name")
 	cmd.Flags().StringP("description", "d", "", "Project description")
+	cmd.Flags().StringVar(&initCmd.templateDir, "template-dir", "", "Generate from a local template directory instead of a cached template")
+	cmd.Flags().StringVar(&initCmd.version, "template-version", "", "Template tag, commit SHA or semver range (e.g. v1.4.0, ^1.2)")
+	cmd.Flags().StringSliceVar(&initCmd.overlays, "with", nil, "Feature overlays to apply on top of the template (e.g. docker,ci)")
+	cmd.Flags().StringArrayVar(&initCmd.setValues, "set", nil, "Set a template variable (key=value, repeatable)")
+	cmd.Flags().StringVar(&initCmd.valuesFile, "values", "", "YAML file with template variable values")
+	cmd.Flags().BoolVar(&initCmd.noHooks, "no-hooks", false, "Skip post-generation hooks (e.g. for CI)")
+	cmd.Flags().BoolVar(&initCmd.trust, "trust", false, "Trust the template and run its hooks without prompting")
+	cmd.Flags().BoolVar(&initCmd.offline, "offline", false, "Use cached templates without contacting remotes")
 	cmd.Flags().BoolVarP(&initCmd.yes, "yes", "y", false, "Skip confirmation prompt")
 
 	initCmd.cmd = cmd
@@ -54,15 +84,90 @@ func (c *InitCommand) Run(cmd *cobra.Command, args []string) error {
 	author, _ := cmd.Flags().GetString("author")
 	description, _ := cmd.Flags().GetString("description")
 
+	// A local template directory does not need an architecture from the registry
+	if c.templateDir != "" {
+		if c.version != "" {
+			return fmt.Errorf("--template-version cannot be used with --template-dir")
+		}
+
+		info, err := os.Stat(c.templateDir)
+		if err != nil {
+			return fmt.Errorf("invalid template directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("template directory is not a directory: %s", c.templateDir)
+		}
+		if c.archType == "" {
+			c.archType = config.LocalArchitecture.String()
+		}
+	} else if c.archType == config.LocalArchitecture.String() {
+		return fmt.Errorf("architecture %s requires --template-dir", c.archType)
+	}
+
 	// Check if running in interactive mode
 	interactiveMode := name == "" || module == "" || c.archType == ""
 
 	var cfg *config.Config
 	var err error
 
+	// Collect template variable values from --values and --set
+	values, err := c.loadVariableValues()
+	if err != nil {
+		return err
+	}
+
+	// Load available templates (defaults plus the user template registry)
+	manager := template.NewManager()
+	if c.offline {
+		manager.SetOffline(true)
+	}
+	templates, err := manager.GetTemplates()
+	if err != nil {
+		return fmt.Errorf("failed to get templates: %w", err)
+	}
+
+	// A registry in replace mode drops the built-in types, so check the loaded templates
+	if c.archType != "" && c.archType != config.LocalArchitecture.String() {
+		if _, err := manager.GetTemplate(config.ArchitectureType(c.archType)); err != nil {
+			return fmt.Errorf("unknown architecture type: %s", c.archType)
+		}
+	}
+
+	if c.archType == config.LocalArchitecture.String() {
+		templates = append(templates, &template.Template{
+			Type:        config.LocalArchitecture,
+			Name:        config.LocalArchitecture.DisplayName(),
+			Description: c.templateDir,
+		})
+	}
+
+	// Get GitHub token from environment
+	token := os.Getenv("PICK_YOUR_GO_GITHUB_TOKEN")
+
+	// Overlays contribute their own variables
+	overlayManifests, err := manager.ResolveOverlayManifests(c.overlays, token)
+	if err != nil {
+		return fmt.Errorf("failed to load overlays: %w", err)
+	}
+
+	// When the template is known up front, its variables are part of the main form
+	var manifest *template.Manifest
+	if interactiveMode && c.archType != "" {
+		manifest, err = manager.ResolveManifest(config.ArchitectureType(c.archType), c.templateDir, c.version, token)
+		if err != nil {
+			return fmt.Errorf("failed to load template manifest: %w", err)
+		}
+		manifest = manifest.WithOverlays(overlayManifests)
+	}
+
 	if interactiveMode {
+		var variables []template.Variable
+		if manifest != nil {
+			variables = manifest.UnsetVariables(values)
+		}
+
 		// Run interactive form
-		cfg, err = ui.RunInitForm(c.archType, name, module, output, author, description)
+		cfg, err = ui.RunInitForm(templates, variables, values, c.archType, name, module, output, author, description)
 		if err != nil {
 			return fmt.Errorf("interactive form failed: %w", err)
 		}
@@ -82,6 +187,39 @@ func (c *InitCommand) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	cfg.TemplateDir = c.templateDir
+	cfg.TemplateVersion = c.version
+	cfg.Overlays = c.overlays
+	cfg.NoHooks = c.noHooks
+	cfg.TrustHooks = c.trust
+	cfg.Offline = manager.IsOffline()
+
+	// The form allows picking a different template than the one given by --architecture
+	if manifest != nil && cfg.Architecture.String() != c.archType {
+		manifest = nil
+	}
+
+	if manifest == nil {
+		manifest, err = manager.ResolveManifest(cfg.Architecture, c.templateDir, c.version, token)
+		if err != nil {
+			return fmt.Errorf("failed to load template manifest: %w", err)
+		}
+		manifest = manifest.WithOverlays(overlayManifests)
+
+		// Prompt for the variables of the template selected in the form
+		if interactiveMode {
+			if err := ui.RunVariablesForm(manifest.UnsetVariables(values), values); err != nil {
+				return fmt.Errorf("interactive form failed: %w", err)
+			}
+		}
+	}
+
+	// Apply defaults and validate the template variables
+	cfg.Variables, err = manifest.ResolveVariables(values)
+	if err != nil {
+		return fmt.Errorf("invalid template variables: %w", err)
+	}
+
 	// Show summary
 	ui.ShowSummary(cfg)
 
@@ -103,20 +241,45 @@ func (c *InitCommand) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Generate the project
-	fmt.Printf("\nGenerating %s project...\n\n", cfg.Architecture.DisplayName())
-
 	factory := generator.NewGeneratorFactory()
 	gen, err := factory.CreateGenerator(cfg.Architecture)
 	if err != nil {
 		return fmt.Errorf("failed to create generator: %w", err)
 	}
 
+	fmt.Printf("\nGenerating %s project...\n\n", cfg.Architecture.DisplayName())
+
 	if err := gen.Generate(cfg); err != nil {
 		return fmt.Errorf("failed to generate project: %w", err)
 	}
 
 	// Show success message
-	ui.ShowSuccess(cfg)
+	ui.ShowSuccess(cfg, gen.GetManifest())
 
 	return nil
 }
+
+// loadVariableValues merges the --values file with --set flags, --set taking precedence
+func (c *InitCommand) loadVariableValues() (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+
+	if c.valuesFile != "" {
+		fileValues, err := template.LoadValuesFile(c.valuesFile)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileValues {
+			values[key] = value
+		}
+	}
+
+	setValues, err := template.ParseSetFlags(c.setValues)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --set flag: %w", err)
+	}
+	for key, value := range setValues {
+		values[key] = value
+	}
+
+	return values, nil
+}
@@ -2,10 +2,15 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/PickHD/pick-your-go/internal/cache"
+	"github.com/PickHD/pick-your-go/internal/config"
 	"github.com/PickHD/pick-your-go/internal/template"
 
 	"github.com/spf13/cobra"
@@ -29,6 +34,9 @@ func NewTemplatesCommand() *cobra.Command {
 	// Add subcommands
 	cmd.AddCommand(templatesCmd.NewListCommand())
 	cmd.AddCommand(templatesCmd.NewUpdateCommand())
+	cmd.AddCommand(templatesCmd.NewLockCommand())
+	cmd.AddCommand(templatesCmd.NewExportCommand())
+	cmd.AddCommand(templatesCmd.NewImportCommand())
 
 	templatesCmd.cmd = cmd
 	return cmd
@@ -41,17 +49,25 @@ type ListCommand struct {
 
 // NewListCommand creates a new list command
 func (c *TemplatesCommand) NewListCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all available templates",
 		Long:  `List all available architecture templates with their descriptions and status.`,
 		RunE:  c.runList,
 	}
+
+	cmd.Flags().Bool("offline", false, "Skip checking remotes for template updates")
+
+	return cmd
 }
 
 // runList executes the list command
 func (c *TemplatesCommand) runList(cmd *cobra.Command, args []string) error {
 	manager := template.NewManager()
+	if offline, _ := cmd.Flags().GetBool("offline"); offline {
+		manager.SetOffline(true)
+	}
+	token := os.Getenv("PICK_YOUR_GO_GITHUB_TOKEN")
 
 	templates, err := manager.GetTemplates()
 	if err != nil {
@@ -62,12 +78,39 @@ func (c *TemplatesCommand) runList(cmd *cobra.Command, args []string) error {
 	fmt.Println("===================")
 
 	for _, tmpl := range templates {
-		status := "  (cached)"
-		if !manager.IsCached(tmpl.Type) {
-			status = "  (not cached)"
+		status := "  (not cached)"
+		if manager.IsCached(tmpl.Type) {
+			available, err := manager.IsUpdateAvailable(tmpl.Type, token)
+			status = cacheStatus(available, err)
+		}
+
+		// Prefer the metadata declared by the template manifest
+		name, description := tmpl.Name, tmpl.Description
+		if manifest, err := manager.GetManifest(tmpl.Type); err == nil {
+			name, description = manifest.Name, manifest.Description
+		}
+
+		fmt.Printf("\n%s - %s%s\n", tmpl.Type.DisplayName(), name, status)
+		fmt.Printf("  Type: %s\n", tmpl.Type)
+		fmt.Printf("  %s\n", description)
+	}
+
+	if overlays := manager.GetOverlays(); len(overlays) > 0 {
+		fmt.Println("\nAvailable Overlays (init --with):")
+		fmt.Println("================================")
+
+		for _, overlay := range overlays {
+			status := "  (not cached)"
+			if manager.IsOverlayCached(overlay) {
+				available, err := manager.IsOverlayUpdateAvailable(overlay, token)
+				status = cacheStatus(available, err)
+			}
+
+			fmt.Printf("\n%s%s\n", overlay.Name, status)
+			if overlay.Description != "" {
+				fmt.Printf("  %s\n", overlay.Description)
+			}
 		}
-		fmt.Printf("\n%s - %s%s\n", tmpl.Type.DisplayName(), tmpl.Name, status)
-		fmt.Printf("  %s\n", tmpl.Description)
 	}
 
 	fmt.Println()
@@ -75,9 +118,25 @@ func (c *TemplatesCommand) runList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// cacheStatus describes a cached template for the list output
+func cacheStatus(updateAvailable bool, err error) string {
+	switch {
+	case err != nil:
+		return "  (cached, update check failed)"
+	case updateAvailable:
+		return "  (cached, update available)"
+	default:
+		return "  (cached)"
+	}
+}
+
+// defaultUpdateJobs is the number of templates updated at the same time
+const defaultUpdateJobs = 4
+
 // UpdateCommand represents the templates update command
 type UpdateCommand struct {
-	cmd *cobra.Command
+	cmd  *cobra.Command
+	jobs int
 }
 
 // NewUpdateCommand creates a new update command
@@ -89,49 +148,326 @@ func (c *TemplatesCommand) NewUpdateCommand() *cobra.Command {
 		Short: "Update template cache from remote repositories",
 		Long: `Update the local template cache by pulling the latest changes from
 remote GitHub repositories. This requires PICK_YOUR_GO_GITHUB_TOKEN
-environment variable to be set for private repositories.`,
+environment variable to be set for private repositories.
+
+Templates and overlays are updated concurrently, the git output of an update
+is only shown when it fails. The command fails when any update failed.`,
+		Example: `  pick-your-go templates update
+  pick-your-go templates update --jobs 8`,
+		Args: cobra.NoArgs,
 		RunE: updateCmd.Run,
 	}
 
+	cmd.Flags().IntVarP(&updateCmd.jobs, "jobs", "j", defaultUpdateJobs, "Number of templates to update at the same time")
+
 	updateCmd.cmd = cmd
 	return cmd
 }
 
+// updateJob is a template or overlay to update
+type updateJob struct {
+	label  string
+	update func(manager *template.Manager) error
+}
+
+// updateResult is the outcome of an update job, or the notice that it started
+type updateResult struct {
+	job     updateJob
+	started bool
+	err     error
+	output  string
+	elapsed time.Duration
+}
+
 // Run executes the update command
 func (c *UpdateCommand) Run(cmd *cobra.Command, args []string) error {
+	if c.jobs < 1 {
+		return fmt.Errorf("invalid --jobs %d, at least one update must run", c.jobs)
+	}
+
 	// Check for GitHub token
 	token := os.Getenv("PICK_YOUR_GO_GITHUB_TOKEN")
 	if token == "" {
 		return fmt.Errorf("PICK_YOUR_GO_GITHUB_TOKEN environment variable is required for accessing private repositories")
 	}
 
-	fmt.Println("Updating template cache...")
-
 	manager := template.NewManager()
-	cacheMgr := cache.NewManager()
+	if manager.IsOffline() {
+		return fmt.Errorf("templates update needs network access, unset %s", config.EnvOffline)
+	}
 
 	templates, err := manager.GetTemplates()
 	if err != nil {
 		return fmt.Errorf("failed to get templates: %w", err)
 	}
 
+	var jobs []updateJob
 	for _, tmpl := range templates {
-		fmt.Printf("\nUpdating %s template...\n", tmpl.Type.DisplayName())
+		jobs = append(jobs, updateJob{
+			label: tmpl.Type.DisplayName() + " template",
+			update: func(manager *template.Manager) error {
+				return manager.UpdateTemplate(tmpl.Type, token)
+			},
+		})
+	}
+	for _, overlay := range manager.GetOverlays() {
+		jobs = append(jobs, updateJob{
+			label: overlay.Name + " overlay",
+			update: func(manager *template.Manager) error {
+				return manager.UpdateOverlay(overlay, token)
+			},
+		})
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("No templates to update")
+		return nil
+	}
+
+	workers := min(c.jobs, len(jobs))
+	fmt.Printf("Updating %d templates and overlays, %d at a time...\n\n", len(jobs), workers)
+
+	var failed []string
+	done := 0
+	for result := range runUpdates(manager, jobs, workers) {
+		if result.started {
+			fmt.Printf("  … updating %s\n", result.job.label)
+			continue
+		}
 
-		if err := manager.UpdateTemplate(tmpl.Type, token); err != nil {
-			fmt.Printf("  Warning: Failed to update %s: %v\n", tmpl.Type.DisplayName(), err)
+		done++
+		if result.err == nil {
+			fmt.Printf("  [%d/%d] ✓ %s updated (%s)\n", done, len(jobs), result.job.label, result.elapsed.Round(100*time.Millisecond))
 			continue
 		}
 
-		// Update cache metadata
-		if err := cacheMgr.UpdateCacheTime(tmpl.Type); err != nil {
-			fmt.Printf("  Warning: Failed to update cache metadata: %v\n", err)
+		failed = append(failed, result.job.label)
+		fmt.Printf("  [%d/%d] ✗ %s failed: %v\n", done, len(jobs), result.job.label, result.err)
+		for _, line := range strings.Split(strings.TrimSpace(result.output), "\n") {
+			if line != "" {
+				fmt.Printf("        %s\n", line)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d updated, %d failed\n", len(jobs)-len(failed), len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d updates failed: %s", len(failed), len(jobs), strings.Join(failed, ", "))
+	}
+
+	fmt.Println("Template cache update completed!")
+
+	return nil
+}
+
+// runUpdates runs update jobs on a pool of workers and streams a notice as each job starts
+// and its result once it finishes.
+// Each worker uses its own clone of the manager and captures the download output of its jobs.
+func runUpdates(manager *template.Manager, jobs []updateJob, workers int) <-chan updateResult {
+	queue := make(chan updateJob)
+	results := make(chan updateResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := manager.Clone()
+			for job := range queue {
+				var output bytes.Buffer
+				worker.SetOutput(&output)
+
+				results <- updateResult{job: job, started: true}
+				start := time.Now()
+				err := job.update(worker)
+				results <- updateResult{job: job, err: err, output: output.String(), elapsed: time.Since(start)}
+			}
+		}()
+	}
+
+	go func() {
+		for _, job := range jobs {
+			queue <- job
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// LockCommand represents the templates lock command
+type LockCommand struct {
+	cmd    *cobra.Command
+	update bool
+}
+
+// NewLockCommand creates a new lock command
+func (c *TemplatesCommand) NewLockCommand() *cobra.Command {
+	lockCmd := &LockCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "lock [template...]",
+		Short: "Pin template commits in pick-your-go.lock",
+		Long: `Resolve templates and overlays to exact commits and record them, together with
+a content hash of their files, in pick-your-go.lock in the current directory.
+Commit the lock file so that init and templates update fetch the same template
+files for the whole team.
+
+Without arguments every template and overlay that is not locked yet is added.
+Use --update to resolve locked entries again from their branch or tag.`,
+		Example: `  pick-your-go templates lock
+  pick-your-go templates lock layered docker
+  pick-your-go templates lock --update`,
+		RunE: lockCmd.Run,
+	}
+
+	cmd.Flags().BoolVar(&lockCmd.update, "update", false, "Re-resolve locked entries to the latest commit of their ref")
+
+	lockCmd.cmd = cmd
+	return cmd
+}
+
+// Run executes the lock command
+func (c *LockCommand) Run(cmd *cobra.Command, args []string) error {
+	path, err := template.GetLockFilePath()
+	if err != nil {
+		return err
+	}
+
+	lock, err := template.LoadLockFile(path)
+	if err != nil {
+		return err
+	}
+
+	manager := template.NewManager()
+	if manager.IsOffline() {
+		return fmt.Errorf("templates lock needs network access, unset %s", config.EnvOffline)
+	}
+
+	entries, err := manager.LockTemplates(lock, args, c.update, os.Getenv("PICK_YOUR_GO_GITHUB_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("All templates are already locked in %s, use --update to re-resolve them\n", lock.Path())
+		return nil
+	}
+
+	if err := lock.Save(); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("  %s@%s -> %s\n", entry.Repository, entry.Ref, entry.Commit)
+	}
+	fmt.Printf("\nWrote %s\n", lock.Path())
+
+	return nil
+}
+
+// ExportCommand represents the templates export command
+type ExportCommand struct {
+	cmd    *cobra.Command
+	output string
+}
+
+// NewExportCommand creates a new export command
+func (c *TemplatesCommand) NewExportCommand() *cobra.Command {
+	exportCmd := &ExportCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "export [template...]",
+		Short: "Package cached templates into a bundle for offline machines",
+		Long: `Package cached templates, with their cache metadata and content hashes, into a
+gzip compressed tar bundle. Install the bundle on a machine without network access
+with 'pick-your-go templates import' and generate projects there with init --offline.
+
+Every cached version of the named templates is exported, together with the parent
+templates they extend. Templates are named as in 'pick-your-go cache info', overlays
+as overlay-<name>. Without arguments the whole cache is exported.`,
+		Example: `  pick-your-go templates export --output bundle.tar.gz
+  pick-your-go templates export layered overlay-docker --output bundle.tar.gz`,
+		RunE: exportCmd.Run,
+	}
+
+	cmd.Flags().StringVarP(&exportCmd.output, "output", "o", "pick-your-go-templates.tar.gz", "Bundle file to write")
+
+	exportCmd.cmd = cmd
+	return cmd
+}
+
+// Run executes the export command
+func (c *ExportCommand) Run(cmd *cobra.Command, args []string) error {
+	manager := template.NewManager()
+
+	keys, err := manager.BundleEntries(args)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("the template cache is empty, run 'pick-your-go templates update' first")
+	}
+
+	file, err := os.Create(c.output)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	cacheMgr := cache.NewManager()
+	if err := cacheMgr.ExportBundle(file, keys); err != nil {
+		file.Close()
+		os.Remove(c.output)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(c.output)
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	for _, key := range keys {
+		if info, err := cacheMgr.GetCacheInfo(key); err == nil {
+			fmt.Printf("  %s @ %s (%s)\n", info.Template, info.Source.Ref, template.ShortCommit(info.Commit))
 		}
+	}
+	fmt.Printf("\nExported %d cache entries to %s\n", len(keys), c.output)
 
-		fmt.Printf("  %s template updated successfully\n", tmpl.Type.DisplayName())
+	return nil
+}
+
+// NewImportCommand creates a new import command
+func (c *TemplatesCommand) NewImportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <bundle>",
+		Short: "Install templates from a bundle into the cache",
+		Long: `Install the templates of a bundle written by 'pick-your-go templates export' into
+the template cache, replacing cached copies of the same source. Every template is
+checked against the content hash recorded in the bundle. No network access is needed.`,
+		Example: `  pick-your-go templates import bundle.tar.gz
+  pick-your-go init -a layered -n myapp -m github.com/user/myapp --offline`,
+		Args: cobra.ExactArgs(1),
+		RunE: c.runImport,
 	}
+}
+
+// runImport executes the import command
+func (c *TemplatesCommand) runImport(cmd *cobra.Command, args []string) error {
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
 
-	fmt.Println("\nTemplate cache update completed!")
+	imported, err := cache.NewManager().ImportBundle(file)
+	for _, info := range imported {
+		fmt.Printf("  %s @ %s (%s)\n", info.Template, info.Source.Ref, template.ShortCommit(info.Commit))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", args[0], err)
+	}
 
+	fmt.Printf("\nImported %d cache entries from %s\n", len(imported), args[0])
 	return nil
 }
@@ -2,6 +2,11 @@
 package cli
 
 import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/PickHD/pick-your-go/internal/cache"
 	"github.com/PickHD/pick-your-go/internal/cli/cmd"
 	"github.com/spf13/cobra"
 )
@@ -21,13 +26,32 @@ a complete, production-ready project structure based on your chosen architecture
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() error {
+	// Archived cache entries are extracted to temporary directories while in use,
+	// which an interrupt would leave behind
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		cache.RemoveExtracted()
+		os.Exit(130)
+	}()
+
 	return rootCmd.Execute()
 }
 
 func init() {
+	rootCmd.PersistentFlags().String("cache-dir", "", "Template cache directory (overrides PICK_YOUR_GO_CACHE_DIR and the cache_dir setting)")
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if dir, _ := cmd.Flags().GetString("cache-dir"); dir != "" {
+			cache.SetCacheDirOverride(dir)
+		}
+	}
+
 	// Add subcommands
 	rootCmd.AddCommand(cmd.NewInitCommand())
 	rootCmd.AddCommand(cmd.NewTemplatesCommand())
+	rootCmd.AddCommand(cmd.NewCacheCommand())
 }
 
 // GetRootCommand returns the root command for testing purposes
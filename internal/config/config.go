@@ -3,7 +3,9 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
 )
 
 // ArchitectureType defines the supported architecture patterns
@@ -16,8 +18,57 @@ const (
 	ModularArchitecture ArchitectureType = "modular"
 	// HexagonalArchitecture represents ports and adapters architecture
 	HexagonalArchitecture ArchitectureType = "hexagonal"
+	// LocalArchitecture represents a template read from a local directory
+	LocalArchitecture ArchitectureType = "local"
 )
 
+const (
+	// ConfigDirName is the name of the user configuration directory
+	ConfigDirName = "pick-your-go"
+	// EnvConfigDir overrides the user configuration directory
+	EnvConfigDir = "PICK_YOUR_GO_CONFIG_DIR"
+	// EnvOffline enables offline mode when set to a true value (1, true)
+	EnvOffline = "PICK_YOUR_GO_OFFLINE"
+)
+
+// architectureInfo holds display information for architectures registered at runtime
+type architectureInfo struct {
+	displayName string
+	description string
+}
+
+// registeredArchitectures holds architectures declared outside of the built-in set
+var registeredArchitectures = make(map[ArchitectureType]architectureInfo)
+
+// RegisterArchitecture makes an additional architecture type known to the application.
+// It is used for templates declared in the user template registry.
+func RegisterArchitecture(a ArchitectureType, displayName, description string) {
+	registeredArchitectures[a] = architectureInfo{
+		displayName: displayName,
+		description: description,
+	}
+}
+
+// GetConfigDir returns the user configuration directory (e.g. ~/.config/pick-your-go)
+func GetConfigDir() (string, error) {
+	if dir := os.Getenv(EnvConfigDir); dir != "" {
+		return dir, nil
+	}
+
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user config directory: %w", err)
+	}
+
+	return filepath.Join(userConfigDir, ConfigDirName), nil
+}
+
+// IsOffline reports whether offline mode is enabled through PICK_YOUR_GO_OFFLINE
+func IsOffline() bool {
+	offline, err := strconv.ParseBool(os.Getenv(EnvOffline))
+	return err == nil && offline
+}
+
 // Config holds the application configuration
 type Config struct {
 	// ProjectName is the name of the Go project to generate
@@ -32,6 +83,20 @@ type Config struct {
 	Author string
 	// Description is the project description
 	Description string
+	// TemplateDir is a local template directory used instead of a cached template
+	TemplateDir string
+	// TemplateVersion pins the template to a tag, commit SHA or semver range of tags
+	TemplateVersion string
+	// Overlays are the feature overlays applied in order on top of the template
+	Overlays []string
+	// Variables holds values for the custom variables declared by the template
+	Variables map[string]interface{}
+	// NoHooks skips the post-generation hooks
+	NoHooks bool
+	// TrustHooks approves hooks of untrusted templates without prompting
+	TrustHooks bool
+	// Offline uses cached templates regardless of age without contacting remotes
+	Offline bool
 }
 
 // Validate checks if the configuration is valid
@@ -88,7 +153,12 @@ func (a ArchitectureType) DisplayName() string {
 		return "Modular Architecture"
 	case HexagonalArchitecture:
 		return "Hexagonal Architecture"
+	case LocalArchitecture:
+		return "Local Template"
 	default:
+		if info, ok := registeredArchitectures[a]; ok {
+			return info.displayName
+		}
 		return "Unknown Architecture"
 	}
 }
@@ -102,7 +172,12 @@ func (a ArchitectureType) Description() string {
 		return "Modular monolith with domain-driven design, organizing code into feature modules"
 	case HexagonalArchitecture:
 		return "Hexagonal architecture (ports and adapters) with isolation of core logic from external concerns"
+	case LocalArchitecture:
+		return "Template copied from a local directory"
 	default:
+		if info, ok := registeredArchitectures[a]; ok {
+			return info.description
+		}
 		return "Unknown architecture pattern"
 	}
 }
@@ -110,9 +185,10 @@ func (a ArchitectureType) Description() string {
 // IsValid checks if the architecture type is valid
 func (a ArchitectureType) IsValid() bool {
 	switch a {
-	case LayeredArchitecture, ModularArchitecture, HexagonalArchitecture:
+	case LayeredArchitecture, ModularArchitecture, HexagonalArchitecture, LocalArchitecture:
 		return true
 	default:
-		return false
+		_, ok := registeredArchitectures[a]
+		return ok
 	}
 }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RefreshNever never checks a cached template for updates, only templates update refreshes it
+	RefreshNever = "never"
+	// RefreshAlways checks a cached template for updates every time it is used
+	RefreshAlways = "always"
+)
+
+// RefreshPolicy decides how often a cached template is checked against its remote
+type RefreshPolicy struct {
+	// Never disables the checks
+	Never bool
+	// TTL is how long a check stays valid, zero checks on every use
+	TTL time.Duration
+}
+
+// DefaultRefreshPolicy checks cached templates at most once a day
+var DefaultRefreshPolicy = RefreshPolicy{TTL: 24 * time.Hour}
+
+// ParseRefreshPolicy parses a refresh policy: never, always or a duration such as 12h or 7d.
+// An empty value yields DefaultRefreshPolicy.
+func ParseRefreshPolicy(value string) (RefreshPolicy, error) {
+	switch strings.TrimSpace(value) {
+	case "":
+		return DefaultRefreshPolicy, nil
+	case RefreshAlways:
+		return RefreshPolicy{}, nil
+	case RefreshNever:
+		return RefreshPolicy{Never: true}, nil
+	}
+
+	ttl, err := ParseDuration(value)
+	if err != nil {
+		return RefreshPolicy{}, fmt.Errorf("invalid refresh policy %q, expected %s, %s or a duration (e.g. 12h, 7d)", value, RefreshNever, RefreshAlways)
+	}
+	return RefreshPolicy{TTL: ttl}, nil
+}
+
+// String returns the policy in the form accepted by ParseRefreshPolicy
+func (p RefreshPolicy) String() string {
+	switch {
+	case p.Never:
+		return RefreshNever
+	case p.TTL == 0:
+		return RefreshAlways
+	default:
+		return p.TTL.String()
+	}
+}
+
+// Expired reports whether a template last checked at lastChecked needs another check
+func (p RefreshPolicy) Expired(lastChecked time.Time) bool {
+	if p.Never {
+		return false
+	}
+	return time.Since(lastChecked) >= p.TTL
+}
+
+// ParseDuration parses a positive duration, accepting days (7d) in addition to time.ParseDuration units
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		d = parsed
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseRefreshPolicy tests parsing refresh policies
+func TestParseRefreshPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected RefreshPolicy
+		wantErr  bool
+	}{
+		{"", RefreshPolicy{TTL: 24 * time.Hour}, false},
+		{"always", RefreshPolicy{}, false},
+		{"never", RefreshPolicy{Never: true}, false},
+		{"12h", RefreshPolicy{TTL: 12 * time.Hour}, false},
+		{"30m", RefreshPolicy{TTL: 30 * time.Minute}, false},
+		{"7d", RefreshPolicy{TTL: 7 * 24 * time.Hour}, false},
+		{"0s", RefreshPolicy{}, true},
+		{"-1h", RefreshPolicy{}, true},
+		{"sometimes", RefreshPolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRefreshPolicy(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRefreshPolicy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseRefreshPolicy(%q) = %+v, expected %+v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+// TestRefreshPolicyExpired tests when cached templates need another check
+func TestRefreshPolicyExpired(t *testing.T) {
+	hourAgo := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		policy   RefreshPolicy
+		expected bool
+	}{
+		{RefreshPolicy{}, true},
+		{RefreshPolicy{Never: true}, false},
+		{RefreshPolicy{TTL: 30 * time.Minute}, true},
+		{RefreshPolicy{TTL: 2 * time.Hour}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.Expired(hourAgo); got != tt.expected {
+			t.Errorf("%s policy: Expired = %v, expected %v", tt.policy, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SettingsFileName is the name of the user settings file in the config directory
+const SettingsFileName = "config.yaml"
+
+const (
+	// CompressionNone stores cache entries as expanded git clones
+	CompressionNone = "none"
+	// CompressionGzip stores cache entries as gzip compressed tar archives
+	CompressionGzip = "gzip"
+	// CompressionZstd stores cache entries as zstd compressed tar archives
+	CompressionZstd = "zstd"
+)
+
+const (
+	// GitBackendGo runs git operations in-process, no git binary is needed
+	GitBackendGo = "go"
+	// GitBackendExec runs the git binary
+	GitBackendExec = "exec"
+)
+
+// Settings holds user preferences read from ~/.config/pick-your-go/config.yaml
+type Settings struct {
+	// Hooks run after every generated project, after the template hooks
+	Hooks []Hook `yaml:"hooks"`
+	// CacheDir is the template cache directory, ~ expands to the home directory
+	CacheDir string `yaml:"cache_dir"`
+	// SystemCacheDirs are read-only template caches shared by all users, looked up before CacheDir
+	SystemCacheDirs []string `yaml:"system_cache_dirs"`
+	// Refresh is the default refresh policy of cached templates: never, always or a duration
+	Refresh string `yaml:"refresh"`
+	// CacheCompression stores cache entries as compressed archives: none, gzip or zstd
+	CacheCompression string `yaml:"cache_compression"`
+	// GitBackend selects how template repositories are cloned and fetched: go (default) or exec
+	GitBackend string `yaml:"git_backend"`
+}
+
+// Hook is a post-generation step run inside the generated project
+type Hook struct {
+	// Name is a short label for the step
+	Name string `yaml:"name"`
+	// Run is the shell command to execute
+	Run string `yaml:"run"`
+}
+
+// GetSettingsPath returns the path of the user settings file
+func GetSettingsPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, SettingsFileName), nil
+}
+
+// LoadSettings reads the user settings file.
+// A missing file is not an error and yields empty settings.
+func LoadSettings() (*Settings, error) {
+	path, err := GetSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadSettingsFile(path)
+}
+
+// LoadSettingsFile reads settings from the given file
+func LoadSettingsFile(path string) (*Settings, error) {
+	settings := &Settings{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings file %s: %w", path, err)
+	}
+
+	if err := ValidateHooks(settings.Hooks); err != nil {
+		return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	if _, err := ParseRefreshPolicy(settings.Refresh); err != nil {
+		return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	switch settings.CacheCompression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("invalid settings file %s: unknown cache_compression %q (expected %s, %s or %s)",
+			path, settings.CacheCompression, CompressionNone, CompressionGzip, CompressionZstd)
+	}
+	switch settings.GitBackend {
+	case "", GitBackendGo, GitBackendExec:
+	default:
+		return nil, fmt.Errorf("invalid settings file %s: unknown git_backend %q (expected %s or %s)",
+			path, settings.GitBackend, GitBackendGo, GitBackendExec)
+	}
+
+	if settings.CacheDir != "" {
+		dir, err := ExpandHome(settings.CacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+		}
+		settings.CacheDir = dir
+	}
+	for i, systemDir := range settings.SystemCacheDirs {
+		dir, err := ExpandHome(systemDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+		}
+		settings.SystemCacheDirs[i] = dir
+	}
+
+	return settings, nil
+}
+
+// ValidateHooks checks hook declarations and defaults their names to the command
+func ValidateHooks(hooks []Hook) error {
+	for i, hook := range hooks {
+		if hook.Run == "" {
+			return fmt.Errorf("hook #%d: run is required", i+1)
+		}
+		if hook.Name == "" {
+			hooks[i].Name = hook.Run
+		}
+	}
+	return nil
+}
+
+// ExpandHome replaces a leading ~ in a path with the home directory
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+
+	return filepath.Join(home, path[1:]), nil
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/PickHD/pick-your-go/internal/config"
+	"github.com/PickHD/pick-your-go/internal/template"
 )
 
 // Generator defines the interface for architecture-specific generators
@@ -18,6 +19,8 @@ type Generator interface {
 	Validate(cfg *config.Config) error
 	// GetStructure returns the directory structure that will be created
 	GetStructure() []string
+	// GetManifest returns the manifest of the template used for generation
+	GetManifest() *template.Manifest
 }
 
 // GeneratorFactory creates generators based on architecture type
@@ -37,8 +40,15 @@ func (f *GeneratorFactory) CreateGenerator(archType config.ArchitectureType) (Ge
 		return NewModularGenerator(), nil
 	case config.HexagonalArchitecture:
 		return NewHexagonalGenerator(), nil
+	case config.LocalArchitecture:
+		return NewTemplateGenerator(config.LocalArchitecture), nil
 	default:
-		return nil, fmt.Errorf("unsupported architecture type: %s", archType)
+		// Fall back to templates declared in the user template registry
+		manager := template.NewManager()
+		if _, err := manager.GetTemplate(archType); err != nil {
+			return nil, fmt.Errorf("unsupported architecture type: %s", archType)
+		}
+		return newTemplateGenerator(manager, archType), nil
 	}
 }
 
@@ -0,0 +1,78 @@
+// Package generator provides architecture-specific generators
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// HookError reports a post-generation hook that exited unsuccessfully
+type HookError struct {
+	Hook     config.Hook
+	ExitCode int
+	Err      error
+}
+
+// Error implements the error interface
+func (e *HookError) Error() string {
+	if e.ExitCode > 0 {
+		return fmt.Sprintf("hook %q failed with exit code %d", e.Hook.Name, e.ExitCode)
+	}
+	return fmt.Sprintf("hook %q failed: %v", e.Hook.Name, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// HookRunner runs post-generation hooks inside the project directory
+type HookRunner struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// NewHookRunner creates a hook runner streaming hook output to the terminal
+func NewHookRunner() *HookRunner {
+	return &HookRunner{
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+}
+
+// Run executes hooks in order and stops at the first failing hook
+func (r *HookRunner) Run(hooks []config.Hook, projectPath string) error {
+	for i, hook := range hooks {
+		fmt.Fprintf(r.stdout, "[%d/%d] %s\n", i+1, len(hooks), hook.Name)
+
+		cmd := shellCommand(hook.Run)
+		cmd.Dir = projectPath
+		cmd.Stdout = r.stdout
+		cmd.Stderr = r.stderr
+
+		if err := cmd.Run(); err != nil {
+			hookErr := &HookError{Hook: hook, Err: err}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				hookErr.ExitCode = exitErr.ExitCode()
+			}
+			return hookErr
+		}
+	}
+
+	return nil
+}
+
+// shellCommand builds a command running a hook through the platform shell
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
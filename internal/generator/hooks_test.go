@@ -0,0 +1,78 @@
+// Package generator provides architecture-specific generators
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// TestHookRunnerRun tests running hooks in order inside the project directory
+func TestHookRunnerRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test use POSIX shell syntax")
+	}
+
+	projectPath := t.TempDir()
+	var stdout bytes.Buffer
+	runner := &HookRunner{stdout: &stdout, stderr: &stdout}
+
+	hooks := []config.Hook{
+		{Name: "first", Run: "echo one > order.txt"},
+		{Name: "second", Run: "echo two >> order.txt && echo streamed"},
+	}
+	if err := runner.Run(hooks, projectPath); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(projectPath, "order.txt"))
+	if err != nil {
+		t.Fatalf("expected hooks to run in the project directory: %v", err)
+	}
+	if string(content) != "one\ntwo\n" {
+		t.Errorf("expected hooks to run in order, got %q", string(content))
+	}
+
+	output := stdout.String()
+	if !strings.Contains(output, "[1/2] first") || !strings.Contains(output, "streamed") {
+		t.Errorf("expected progress and hook output to be streamed, got %q", output)
+	}
+}
+
+// TestHookRunnerFailure tests that a failing hook reports its exit code and stops the run
+func TestHookRunnerFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test use POSIX shell syntax")
+	}
+
+	projectPath := t.TempDir()
+	var stdout bytes.Buffer
+	runner := &HookRunner{stdout: &stdout, stderr: &stdout}
+
+	hooks := []config.Hook{
+		{Name: "fail", Run: "exit 3"},
+		{Name: "never", Run: "touch never.txt"},
+	}
+	err := runner.Run(hooks, projectPath)
+
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("expected HookError, got %v", err)
+	}
+	if hookErr.ExitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", hookErr.ExitCode)
+	}
+	if !strings.Contains(err.Error(), "exit code 3") {
+		t.Errorf("expected error to mention the exit code, got %q", err.Error())
+	}
+
+	if _, err := os.Stat(filepath.Join(projectPath, "never.txt")); !os.IsNotExist(err) {
+		t.Error("expected hooks after a failure not to run")
+	}
+}
@@ -0,0 +1,436 @@
+// Package generator provides architecture-specific generators
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+	"github.com/PickHD/pick-your-go/internal/template"
+	"github.com/PickHD/pick-your-go/pkg/ui"
+)
+
+// TemplateGenerator generates projects from a cached template repository.
+// The architecture-specific generators embed it, and it is used directly
+// for templates declared in the user template registry.
+type TemplateGenerator struct {
+	*BaseGenerator
+	templateManager *template.Manager
+	archType        config.ArchitectureType
+	manifest        *template.Manifest
+	overlays        []*overlayLayer
+	// parentModules are the module paths of the templates the template extends
+	parentModules []string
+}
+
+// overlayLayer is an overlay selected for generation together with its manifest
+type overlayLayer struct {
+	overlay  *template.Overlay
+	manifest *template.Manifest
+	// module is the module path declared by the overlay go.mod, rewritten in imports
+	module string
+}
+
+// NewTemplateGenerator creates a generator for the given architecture type
+func NewTemplateGenerator(archType config.ArchitectureType) *TemplateGenerator {
+	return newTemplateGenerator(template.NewManager(), archType)
+}
+
+// newTemplateGenerator creates a generator backed by an existing template manager
+func newTemplateGenerator(manager *template.Manager, archType config.ArchitectureType) *TemplateGenerator {
+	return &TemplateGenerator{
+		BaseGenerator:   NewBaseGenerator(),
+		templateManager: manager,
+		archType:        archType,
+	}
+}
+
+// Generate creates a project from the template
+func (g *TemplateGenerator) Generate(cfg *config.Config) error {
+	if err := g.ValidateConfig(cfg); err != nil {
+		return err
+	}
+
+	projectPath := g.GetProjectPath(cfg)
+
+	// Check if directory already exists
+	if _, err := os.Stat(projectPath); err == nil {
+		return fmt.Errorf("directory already exists: %s", projectPath)
+	}
+
+	// The template paths read while preparing stay valid until the project is generated
+	defer g.templateManager.Hold()()
+
+	if err := g.prepareTemplate(cfg); err != nil {
+		return err
+	}
+
+	// Decide on the hooks before anything is written, so an untrusted
+	// template does not leave a half-finished project behind
+	hooks, err := g.resolveHooks(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := g.copyTemplate(cfg, projectPath); err != nil {
+		return err
+	}
+
+	if err := g.applyOverlays(cfg, projectPath); err != nil {
+		return err
+	}
+
+	// Customize project-specific files
+	fmt.Println("Customizing project files...")
+	if err := g.customizeProject(cfg, projectPath); err != nil {
+		return fmt.Errorf("failed to customize project: %w", err)
+	}
+
+	if len(hooks) > 0 {
+		fmt.Println("Running post-generation hooks...")
+		if err := NewHookRunner().Run(hooks, projectPath); err != nil {
+			return fmt.Errorf("post-generation hook failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// resolveHooks returns the template hooks followed by the user hooks.
+// Template hooks are only included once the template source is trusted.
+func (g *TemplateGenerator) resolveHooks(cfg *config.Config) ([]config.Hook, error) {
+	if cfg.NoHooks {
+		return nil, nil
+	}
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	var hooks []config.Hook
+
+	if templateHooks := g.manifest.Hooks; len(templateHooks) > 0 {
+		var source template.TemplateSource
+		if cfg.TemplateDir != "" {
+			source, err = template.GetDirectorySource(cfg.TemplateDir, templateHooks)
+		} else {
+			source, err = g.templateManager.GetTemplateSource(g.archType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to identify template source: %w", err)
+		}
+
+		trusted, err := g.trustTemplateHooks(cfg, source, templateHooks)
+		if err != nil {
+			return nil, err
+		}
+		if trusted {
+			hooks = append(hooks, templateHooks...)
+		} else {
+			fmt.Println("Skipping template hooks (not trusted)")
+		}
+	}
+
+	// Every overlay is a separate source and needs its own approval
+	for _, layer := range g.overlays {
+		overlayHooks := layer.manifest.Hooks
+		if len(overlayHooks) == 0 {
+			continue
+		}
+
+		source, err := g.templateManager.GetOverlaySource(layer.overlay, overlayHooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to identify overlay source: %w", err)
+		}
+
+		trusted, err := g.trustTemplateHooks(cfg, source, overlayHooks)
+		if err != nil {
+			return nil, err
+		}
+		if trusted {
+			hooks = append(hooks, overlayHooks...)
+		} else {
+			fmt.Printf("Skipping %s overlay hooks (not trusted)\n", layer.overlay.Name)
+		}
+	}
+
+	hooks = append(hooks, settings.Hooks...)
+
+	return hooks, nil
+}
+
+// trustTemplateHooks checks the trust store for the template source, asking the user
+// to approve unknown or changed templates. Approvals are recorded in the trust store.
+func (g *TemplateGenerator) trustTemplateHooks(cfg *config.Config, source template.TemplateSource, hooks []config.Hook) (bool, error) {
+	store, err := template.LoadTrustStore()
+	if err != nil {
+		return false, err
+	}
+
+	if store.IsTrusted(source) {
+		return true, nil
+	}
+
+	if !cfg.TrustHooks {
+		if !ui.IsInteractive() {
+			return false, fmt.Errorf("template %s is not trusted to run hooks; re-run with --trust to approve or --no-hooks to skip them", source.Repository)
+		}
+
+		approved, err := ui.ConfirmHookTrust(source.Repository, hooks)
+		if err != nil {
+			return false, fmt.Errorf("trust confirmation failed: %w", err)
+		}
+		if !approved {
+			return false, nil
+		}
+	}
+
+	store.Trust(source)
+	if err := store.Save(); err != nil {
+		return false, fmt.Errorf("failed to record trusted template: %w", err)
+	}
+
+	return true, nil
+}
+
+// prepareTemplate makes the template and the selected overlays available and loads their manifests.
+// A local template directory bypasses the cache and the GitHub token entirely.
+func (g *TemplateGenerator) prepareTemplate(cfg *config.Config) error {
+	// Get GitHub token from environment
+	token := os.Getenv("PICK_YOUR_GO_GITHUB_TOKEN")
+
+	if cfg.Offline {
+		g.templateManager.SetOffline(true)
+	}
+
+	if err := g.prepareOverlays(cfg, token); err != nil {
+		return err
+	}
+
+	if cfg.TemplateDir != "" {
+		templateDir, err := filepath.Abs(cfg.TemplateDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve template directory: %w", err)
+		}
+
+		if err := g.templateManager.EnsureParentsCached(templateDir, token); err != nil {
+			return fmt.Errorf("failed to ensure parent templates are cached: %w", err)
+		}
+
+		manifest, err := g.templateManager.GetDirectoryManifest(templateDir, g.archType)
+		if err != nil {
+			return fmt.Errorf("failed to load template manifest: %w", err)
+		}
+		g.manifest = manifest
+
+		return g.prepareParents(templateDir)
+	}
+
+	// Ensure template is cached
+	fmt.Println("Ensuring template is cached...")
+	if err := g.templateManager.EnsureTemplateVersionCached(g.archType, cfg.TemplateVersion, token); err != nil {
+		return fmt.Errorf("failed to ensure template is cached: %w", err)
+	}
+
+	manifest, err := g.templateManager.GetManifest(g.archType)
+	if err != nil {
+		return fmt.Errorf("failed to load template manifest: %w", err)
+	}
+	g.manifest = manifest
+
+	templatePath, err := g.templateManager.GetTemplatePath(g.archType)
+	if err != nil {
+		return err
+	}
+
+	return g.prepareParents(templatePath)
+}
+
+// prepareParents records the module paths of the templates the template extends,
+// so that imports of parent packages can be rewritten to the project module
+func (g *TemplateGenerator) prepareParents(templateDir string) error {
+	chain, err := g.templateManager.GetTemplateChain(templateDir)
+	if err != nil {
+		return err
+	}
+
+	g.parentModules = nil
+	for _, dir := range chain[:len(chain)-1] {
+		goModPath := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(goModPath); err != nil {
+			continue
+		}
+
+		module, err := extractOriginalModulePath(goModPath)
+		if err != nil {
+			return fmt.Errorf("failed to read parent template module path: %w", err)
+		}
+		g.parentModules = append(g.parentModules, module)
+	}
+
+	return nil
+}
+
+// prepareOverlays makes the selected overlays available and loads their manifests
+func (g *TemplateGenerator) prepareOverlays(cfg *config.Config, token string) error {
+	overlays, err := g.templateManager.GetOverlaysByName(cfg.Overlays)
+	if err != nil {
+		return err
+	}
+
+	g.overlays = nil
+	for _, overlay := range overlays {
+		if overlay.Dir == "" {
+			fmt.Printf("Ensuring %s overlay is cached...\n", overlay.Name)
+		}
+		if err := g.templateManager.EnsureOverlayCached(overlay, token); err != nil {
+			return fmt.Errorf("failed to ensure overlay %s is cached: %w", overlay.Name, err)
+		}
+
+		manifest, err := g.templateManager.GetOverlayManifest(overlay)
+		if err != nil {
+			return fmt.Errorf("failed to load overlay manifest: %w", err)
+		}
+
+		g.overlays = append(g.overlays, &overlayLayer{overlay: overlay, manifest: manifest})
+	}
+
+	return nil
+}
+
+// applyOverlays applies the selected overlays in order on top of the copied template
+func (g *TemplateGenerator) applyOverlays(cfg *config.Config, projectPath string) error {
+	data := template.NewRenderData(cfg)
+
+	for _, layer := range g.overlays {
+		overlayPath, err := g.templateManager.GetOverlayPath(layer.overlay)
+		if err != nil {
+			return err
+		}
+
+		// Remember the overlay module so its imports can be rewritten with the template imports
+		goModPath := filepath.Join(overlayPath, "go.mod")
+		if _, err := os.Stat(goModPath); err == nil {
+			if layer.module, err = extractOriginalModulePath(goModPath); err != nil {
+				return fmt.Errorf("failed to read %s overlay module path: %w", layer.overlay.Name, err)
+			}
+		}
+
+		fmt.Printf("Applying %s overlay...\n", layer.overlay.Name)
+		if err := g.templateManager.ApplyOverlay(layer.overlay, projectPath, data); err != nil {
+			return fmt.Errorf("failed to apply %s overlay: %w", layer.overlay.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// copyTemplate copies the prepared template into the project directory
+func (g *TemplateGenerator) copyTemplate(cfg *config.Config, projectPath string) error {
+	data := template.NewRenderData(cfg)
+
+	if cfg.TemplateDir != "" {
+		templateDir, err := filepath.Abs(cfg.TemplateDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve template directory: %w", err)
+		}
+
+		fmt.Printf("Copying local template from %s...\n", templateDir)
+		if err := g.templateManager.CopyDirectoryToDestination(templateDir, projectPath, data); err != nil {
+			return fmt.Errorf("failed to copy template: %w", err)
+		}
+		return nil
+	}
+
+	// Copy template to destination
+	fmt.Println("Copying template to destination...")
+	if err := g.templateManager.CopyTemplateToDestination(g.archType, projectPath, data); err != nil {
+		return fmt.Errorf("failed to copy template: %w", err)
+	}
+
+	return nil
+}
+
+// Validate checks if the configuration is valid for this template
+func (g *TemplateGenerator) Validate(cfg *config.Config) error {
+	return g.ValidateConfig(cfg)
+}
+
+// GetStructure returns the directory structure that will be created
+func (g *TemplateGenerator) GetStructure() []string {
+	return g.GetManifest().Structure
+}
+
+// GetManifest returns the manifest of the template used for generation, combined with its overlays.
+// Before Generate is called, the manifest of the cached template (or the built-in default) is returned.
+func (g *TemplateGenerator) GetManifest() *template.Manifest {
+	if g.manifest != nil {
+		overlays := make([]*template.Manifest, 0, len(g.overlays))
+		for _, layer := range g.overlays {
+			overlays = append(overlays, layer.manifest)
+		}
+		return g.manifest.WithOverlays(overlays)
+	}
+
+	manifest, err := g.templateManager.GetManifest(g.archType)
+	if err != nil {
+		fmt.Printf("Warning: failed to load template manifest: %v\n", err)
+		return &template.Manifest{Name: g.archType.DisplayName()}
+	}
+
+	return manifest
+}
+
+// customizeProject customizes the project with user-specific information
+func (g *TemplateGenerator) customizeProject(cfg *config.Config, projectPath string) error {
+
+	// Verify projectPath is absolute
+	if !filepath.IsAbs(projectPath) {
+		return fmt.Errorf("BUG: projectPath is not absolute: %s", projectPath)
+	}
+
+	// Update go.mod with correct module path
+	goModPath := filepath.Join(projectPath, "go.mod")
+
+	// CRITICAL: Extract original module path BEFORE updating go.mod
+	oldModule, err := extractOriginalModulePath(goModPath)
+	if err != nil {
+		return fmt.Errorf("failed to extract original module path: %w", err)
+	}
+
+	if err := updateGoModule(goModPath, cfg.ModulePath); err != nil {
+		fmt.Printf("Warning: failed to update go.mod: %v\n", err)
+		// Don't return error here, just warn
+	}
+
+	// CRITICAL: Update all import paths in .go files
+	// This is necessary because the template uses its own module name in imports
+	if oldModule != cfg.ModulePath {
+		fmt.Println("Updating import paths in Go files...")
+		if err := updateImportPaths(projectPath, oldModule, cfg.ModulePath); err != nil {
+			return fmt.Errorf("failed to update import paths: %w", err)
+		}
+		fmt.Printf("Successfully updated import paths from '%s' to '%s'\n", oldModule, cfg.ModulePath)
+	}
+
+	// Parent templates and overlays may import packages through their own module path
+	modules := append([]string{}, g.parentModules...)
+	for _, layer := range g.overlays {
+		modules = append(modules, layer.module)
+	}
+	rewritten := map[string]bool{oldModule: true, cfg.ModulePath: true}
+	for _, module := range modules {
+		if module == "" || rewritten[module] {
+			continue
+		}
+		rewritten[module] = true
+
+		if err := updateImportPaths(projectPath, module, cfg.ModulePath); err != nil {
+			return fmt.Errorf("failed to update import paths of %s: %w", module, err)
+		}
+	}
+
+	return nil
+}
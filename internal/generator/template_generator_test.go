@@ -0,0 +1,68 @@
+// Package generator provides architecture-specific generators
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PickHD/pick-your-go/internal/cache"
+	"github.com/PickHD/pick-your-go/internal/config"
+	"github.com/PickHD/pick-your-go/internal/template"
+)
+
+// TestGenerateFromTemplateDir tests generating a project from a local template directory
+func TestGenerateFromTemplateDir(t *testing.T) {
+	t.Setenv(config.EnvConfigDir, t.TempDir())
+	t.Setenv(cache.EnvCacheDir, t.TempDir())
+	t.Setenv(cache.EnvSystemCacheDirs, "")
+	t.Setenv(template.EnvLockFile, filepath.Join(t.TempDir(), template.LockFileName))
+
+	templateDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":              "module github.com/acme/template\n\ngo 1.25\n",
+		"main.go":             "package main\n\nimport \"github.com/acme/template/internal/app\"\n\nfunc main() { app.Run() }\n",
+		"internal/app/app.go": "package app\n\n// Run starts the application\nfunc Run() {}\n",
+		"README.md":           "# template\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(templateDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		ProjectName:  "demo",
+		ModulePath:   "example.com/team/demo",
+		Architecture: config.LocalArchitecture,
+		OutputDir:    t.TempDir(),
+		TemplateDir:  templateDir,
+		NoHooks:      true,
+	}
+	if err := NewTemplateGenerator(config.LocalArchitecture).Generate(cfg); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	projectPath := cfg.GetProjectPath()
+	module, err := extractOriginalModulePath(filepath.Join(projectPath, "go.mod"))
+	if err != nil || module != cfg.ModulePath {
+		t.Errorf("expected module %s, got %s (%v)", cfg.ModulePath, module, err)
+	}
+
+	mainGo, err := os.ReadFile(filepath.Join(projectPath, "main.go"))
+	if err != nil {
+		t.Fatalf("expected main.go to be copied: %v", err)
+	}
+	if !strings.Contains(string(mainGo), `"example.com/team/demo/internal/app"`) ||
+		strings.Contains(string(mainGo), "github.com/acme/template") {
+		t.Errorf("expected imports to be rewritten, got:\n%s", mainGo)
+	}
+	if _, err := os.Stat(filepath.Join(projectPath, "internal", "app", "app.go")); err != nil {
+		t.Errorf("expected nested files to be copied: %v", err)
+	}
+}
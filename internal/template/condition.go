@@ -0,0 +1,47 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Condition includes a path only when its expression evaluates to true
+type Condition struct {
+	// Path is a glob pattern (see MatchPath) selecting the conditional files and directories
+	Path string `yaml:"path"`
+	// When is a text/template pipeline deciding whether the path is generated, e.g. eq .cache "redis"
+	When string `yaml:"when"`
+}
+
+// text wraps the expression in an if action, so that the truth rules of text/template apply
+func (c *Condition) text() string {
+	return "{{if " + c.When + "}}true{{end}}"
+}
+
+// compile checks the condition syntax
+func (c *Condition) compile() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if strings.TrimSpace(c.When) == "" {
+		return fmt.Errorf("condition for %q: when is required", c.Path)
+	}
+
+	if _, err := texttemplate.New(c.Path).Funcs(renderFuncs).Parse(c.text()); err != nil {
+		return fmt.Errorf("condition for %q: invalid expression %q: %w", c.Path, c.When, err)
+	}
+
+	return nil
+}
+
+// Evaluate reports whether the condition holds for the render data.
+// Variables that are neither declared nor config fields are an error rather than false.
+func (c *Condition) Evaluate(data RenderData) (bool, error) {
+	out, err := renderString(fmt.Sprintf("condition for %q", c.Path), c.text(), data)
+	if err != nil {
+		return false, err
+	}
+
+	return out == "true", nil
+}
@@ -0,0 +1,120 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEvaluateCondition tests evaluating condition expressions against template variables
+func TestEvaluateCondition(t *testing.T) {
+	data := RenderData{
+		"cache":       "redis",
+		"metrics":     true,
+		"tracing":     false,
+		"features":    []string{"docker", "ci"},
+		"ProjectName": "myapp",
+	}
+
+	tests := []struct {
+		when string
+		want bool
+	}{
+		{`eq .cache "redis"`, true},
+		{`eq .cache "memcached"`, false},
+		{`ne .cache "none"`, true},
+		{`.metrics`, true},
+		{`not .tracing`, true},
+		{`and .metrics .tracing`, false},
+		{`or .metrics .tracing`, true},
+		{`has .features "docker"`, true},
+		{`has .features "otel"`, false},
+		{`and (not (has .features "otel")) (eq .cache "redis")`, true},
+		{`and (or (eq .cache "redis") (eq .cache "memcached")) (eq .ProjectName "myapp")`, true},
+	}
+
+	for _, tt := range tests {
+		condition := Condition{Path: "x", When: tt.when}
+		if err := condition.compile(); err != nil {
+			t.Errorf("compile(%q) failed: %v", tt.when, err)
+			continue
+		}
+		got, err := condition.Evaluate(data)
+		if err != nil {
+			t.Errorf("Evaluate(%q) failed: %v", tt.when, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.when, got, tt.want)
+		}
+	}
+
+	// A misspelled variable must not silently drop the path
+	for _, when := range []string{`.cahce`, `eq .cahce "redis"`} {
+		if _, err := (&Condition{Path: "x", When: when}).Evaluate(data); err == nil {
+			t.Errorf("Evaluate(%q): expected error for an undeclared variable, got nil", when)
+		}
+	}
+}
+
+// TestConditionInvalid tests syntax errors in condition expressions
+func TestConditionInvalid(t *testing.T) {
+	for _, when := range []string{
+		`cache == "redis"`,
+		`eq .cache "redis`,
+		`(eq .cache "redis"`,
+		`{{ .cache }}`,
+		`unknown .cache`,
+		``,
+	} {
+		if err := (&Condition{Path: "x", When: when}).compile(); err == nil {
+			t.Errorf("compile(%q): expected error, got nil", when)
+		}
+	}
+}
+
+// TestCopyDirectoryConditionalPaths tests skipping paths whose condition is false
+func TestCopyDirectoryConditionalPaths(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		ManifestFileName: `conditions:
+  - path: internal/infrastructure/cache/**
+    when: eq .cache "redis"
+  - path: Dockerfile
+    when: has .features "docker"
+`,
+		"go.mod":                                 "module example.com/tpl\n",
+		"internal/infrastructure/cache/redis.go": "package cache\n",
+		"internal/infrastructure/database/db.go": "package database\n",
+		"Dockerfile":                             "FROM golang\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	destDir := filepath.Join(t.TempDir(), "project")
+	data := RenderData{"cache": "none", "features": []string{"docker"}}
+
+	m := &Manager{}
+	if err := m.CopyDirectoryToDestination(srcDir, destDir, data); err != nil {
+		t.Fatalf("CopyDirectoryToDestination failed: %v", err)
+	}
+
+	for name, wantExists := range map[string]bool{
+		"go.mod":                                 true,
+		"Dockerfile":                             true,
+		"internal/infrastructure/database/db.go": true,
+		"internal/infrastructure/cache":          false,
+	} {
+		_, err := os.Stat(filepath.Join(destDir, name))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("%s: exists = %v, want %v", name, exists, wantExists)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// maxExtendsDepth limits the length of a template inheritance chain
+const maxExtendsDepth = 10
+
+// ParentRef is a parsed extends declaration: <template source>@<ref>
+type ParentRef struct {
+	// Source is a registry template type, a repository URL or a directory relative to the child template
+	Source string
+	// Ref is the branch or tag of the parent, empty for the default branch
+	Ref string
+}
+
+// ParseExtends parses an extends declaration such as
+// https://github.com/PickHD/go-layered-template@v1.2.0 or layered@main
+func ParseExtends(value string) (ParentRef, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ParentRef{}, fmt.Errorf("extends is empty")
+	}
+
+	// The ref separator is the last @ after the path, so git@host:org/repo stays intact
+	at := strings.LastIndex(value, "@")
+	if at > strings.LastIndexAny(value, "/:") {
+		ref := ParentRef{Source: value[:at], Ref: value[at+1:]}
+		if ref.Source == "" || ref.Ref == "" {
+			return ParentRef{}, fmt.Errorf("invalid extends %q, expected <template source>@<ref>", value)
+		}
+		return ref, nil
+	}
+
+	return ParentRef{Source: value}, nil
+}
+
+// isLocal reports whether the parent is a directory next to the child template
+func (p ParentRef) isLocal() bool {
+	return strings.HasPrefix(p.Source, ".") || filepath.IsAbs(p.Source)
+}
+
+// parentTemplate is a resolved parent template
+type parentTemplate struct {
+	// dir holds the files of a local parent, cached parents are found with cacheDir
+	dir string
+	// template is the repository to clone, nil for local parents
+	template *Template
+	// locked is the template at the ref of its cache entry, the locked commit if any
+	locked *Template
+}
+
+// resolveParent resolves the extends declaration of the template in childDir
+func (m *Manager) resolveParent(childDir, extends string) (*parentTemplate, error) {
+	ref, err := ParseExtends(extends)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.isLocal() {
+		dir := ref.Source
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(childDir, dir)
+		}
+		return &parentTemplate{dir: filepath.Clean(dir)}, nil
+	}
+
+	// Registry template types stand for their repository
+	parent := &Template{
+		Type:       config.ArchitectureType(ref.Source),
+		Name:       ref.Source,
+		Repository: ref.Source,
+		Branch:     "main",
+	}
+	if tmpl, err := m.GetTemplate(config.ArchitectureType(ref.Source)); err == nil {
+		parent.Repository, parent.Branch, parent.Subdir = tmpl.Repository, tmpl.Branch, tmpl.Subdir
+	}
+	if ref.Ref != "" {
+		parent.Branch = ref.Ref
+	}
+
+	locked, _, err := m.lockedTemplate(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parentTemplate{template: parent, locked: locked}, nil
+}
+
+// resolveChain returns the template directories of the inheritance chain of templateDir,
+// the root parent first and templateDir last. With fetch set, missing or expired parents
+// are downloaded; otherwise they must already be cached.
+func (m *Manager) resolveChain(templateDir, token string, fetch bool) ([]string, error) {
+	dir, err := filepath.Abs(templateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve template directory: %w", err)
+	}
+
+	chain := []string{dir}
+	seen := map[string]bool{dir: true}
+	for {
+		manifest, err := LoadManifest(dir)
+		if err != nil {
+			return nil, err
+		}
+		if manifest == nil || manifest.Extends == "" {
+			return chain, nil
+		}
+		if len(chain) > maxExtendsDepth {
+			return nil, fmt.Errorf("template inheritance is deeper than %d levels", maxExtendsDepth)
+		}
+
+		parent, err := m.resolveParent(dir, manifest.Extends)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.template != nil {
+			if fetch {
+				if _, err := m.ensureCached(parent.template, token); err != nil {
+					return nil, fmt.Errorf("failed to fetch parent template %s: %w", manifest.Extends, err)
+				}
+			}
+			if parent.dir, err = m.entryDir(parent.locked.cacheKey(), parent.locked); err != nil {
+				return nil, err
+			}
+		}
+
+		if info, err := os.Stat(parent.dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("parent template %s is not available", manifest.Extends)
+		}
+		if seen[parent.dir] {
+			return nil, fmt.Errorf("template inheritance cycle at %s", manifest.Extends)
+		}
+		seen[parent.dir] = true
+
+		chain = append([]string{parent.dir}, chain...)
+		dir = parent.dir
+	}
+}
+
+// EnsureParentsCached downloads the parent templates the template in templateDir extends
+func (m *Manager) EnsureParentsCached(templateDir, token string) error {
+	defer m.Hold()()
+
+	_, err := m.resolveChain(templateDir, token, true)
+	return err
+}
+
+// GetTemplateChain returns the inheritance chain of a template directory,
+// the root parent first and templateDir last. Parents must already be cached, archived
+// parents are extracted until the enclosing Hold is released.
+func (m *Manager) GetTemplateChain(templateDir string) ([]string, error) {
+	defer m.Hold()()
+
+	return m.resolveChain(templateDir, "", false)
+}
+
+// loadChainManifest loads the manifest of a template directory including what it inherits.
+// It returns nil without error when no template in the chain ships a manifest.
+func (m *Manager) loadChainManifest(templateDir string) (*Manifest, error) {
+	chain, err := m.GetTemplateChain(templateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var combined *Manifest
+	for _, dir := range chain {
+		manifest, err := LoadManifest(dir)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case manifest == nil:
+		case combined == nil:
+			combined = manifest
+		default:
+			combined = combined.ExtendedBy(manifest)
+		}
+	}
+
+	return combined, nil
+}
+
+// ExtendedBy returns the manifest of a child template inheriting from mf.
+// Metadata, structure, next steps and notes of the child replace the parent's when set,
+// child variables override parent variables with the same name and hooks run parent first.
+// Exclude, conditions and merge rules are not inherited, they apply to the files of their own template.
+func (mf *Manifest) ExtendedBy(child *Manifest) *Manifest {
+	combined := *child
+
+	if combined.Name == "" {
+		combined.Name = mf.Name
+	}
+	if combined.Description == "" {
+		combined.Description = mf.Description
+	}
+	if len(combined.Structure) == 0 {
+		combined.Structure = mf.Structure
+	}
+	if len(combined.NextSteps) == 0 {
+		combined.NextSteps = mf.NextSteps
+	}
+	if len(combined.Notes) == 0 {
+		combined.Notes = mf.Notes
+	}
+
+	overrides := make(map[string]Variable)
+	for _, v := range child.Variables {
+		overrides[v.Name] = v
+	}
+	combined.Variables = nil
+	for _, v := range mf.Variables {
+		if override, ok := overrides[v.Name]; ok {
+			v = override
+			delete(overrides, v.Name)
+		}
+		combined.Variables = append(combined.Variables, v)
+	}
+	for _, v := range child.Variables {
+		if _, ok := overrides[v.Name]; ok {
+			combined.Variables = append(combined.Variables, v)
+		}
+	}
+
+	combined.Hooks = append(append([]config.Hook{}, mf.Hooks...), child.Hooks...)
+
+	return &combined
+}
@@ -0,0 +1,150 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseExtends tests parsing extends declarations
+func TestParseExtends(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ParentRef
+		wantErr  bool
+	}{
+		{"layered", ParentRef{Source: "layered"}, false},
+		{"layered@main", ParentRef{Source: "layered", Ref: "main"}, false},
+		{"https://github.com/PickHD/go-layered-template@v1.2.0", ParentRef{Source: "https://github.com/PickHD/go-layered-template", Ref: "v1.2.0"}, false},
+		{"git@github.com:PickHD/go-layered-template.git", ParentRef{Source: "git@github.com:PickHD/go-layered-template.git"}, false},
+		{"git@github.com:PickHD/go-layered-template.git@develop", ParentRef{Source: "git@github.com:PickHD/go-layered-template.git", Ref: "develop"}, false},
+		{"../base", ParentRef{Source: "../base"}, false},
+		{"layered@", ParentRef{}, true},
+		{"", ParentRef{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseExtends(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseExtends(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseExtends(%q) = %+v, expected %+v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+// TestCopyExtendedTemplate tests that child files are applied over the files of the parent chain
+func TestCopyExtendedTemplate(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, filepath.Join(root, "base"), map[string]string{
+		ManifestFileName: "name: Base\nnotes:\n  - from base\n",
+		"go.mod":         "module github.com/example/base\n",
+		"main.go":        "package main // base\n",
+		"internal/db.go": "package internal\n",
+		"docs/legacy.md": "legacy\n",
+		"README.md.tmpl": "# {{.ProjectName}} (base)\n",
+	})
+	writeFiles(t, filepath.Join(root, "middle"), map[string]string{
+		ManifestFileName: "extends: ../base\nexclude:\n  - docs/legacy.md\n",
+		"main.go":        "package main // middle\n",
+	})
+	writeFiles(t, filepath.Join(root, "child"), map[string]string{
+		ManifestFileName: "name: Child\nextends: ../middle\n",
+		"README.md.tmpl": "# {{.ProjectName}} (child)\n",
+	})
+
+	manager := &Manager{}
+	chain, err := manager.GetTemplateChain(filepath.Join(root, "child"))
+	if err != nil {
+		t.Fatalf("GetTemplateChain failed: %v", err)
+	}
+	if len(chain) != 3 || filepath.Base(chain[0]) != "base" || filepath.Base(chain[2]) != "child" {
+		t.Fatalf("unexpected chain: %v", chain)
+	}
+
+	dest := filepath.Join(t.TempDir(), "project")
+	if err := manager.CopyDirectoryToDestination(filepath.Join(root, "child"), dest, RenderData{"ProjectName": "demo"}); err != nil {
+		t.Fatalf("CopyDirectoryToDestination failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"go.mod":         "module github.com/example/base\n",
+		"main.go":        "package main // middle\n",
+		"internal/db.go": "package internal\n",
+		"README.md":      "# demo (child)\n",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Errorf("expected %s to be generated: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, expected %q", name, data, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "docs", "legacy.md")); !os.IsNotExist(err) {
+		t.Errorf("parent file excluded by a child should not be copied")
+	}
+
+	manifest, err := manager.loadChainManifest(filepath.Join(root, "child"))
+	if err != nil {
+		t.Fatalf("loadChainManifest failed: %v", err)
+	}
+	if manifest.Name != "Child" || len(manifest.Notes) != 1 || manifest.Notes[0] != "from base" {
+		t.Errorf("unexpected inherited manifest: %+v", manifest)
+	}
+}
+
+// TestTemplateChainCycle tests that inheritance cycles are rejected
+func TestTemplateChainCycle(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, filepath.Join(root, "a"), map[string]string{ManifestFileName: "extends: ../b\n"})
+	writeFiles(t, filepath.Join(root, "b"), map[string]string{ManifestFileName: "extends: ../a\n"})
+
+	if _, err := (&Manager{}).GetTemplateChain(filepath.Join(root, "a")); err == nil {
+		t.Errorf("expected an error for an inheritance cycle")
+	}
+}
+
+// TestManifestExtendedBy tests how a child manifest inherits from its parent
+func TestManifestExtendedBy(t *testing.T) {
+	parent := &Manifest{
+		Name:      "Base",
+		Structure: []string{"cmd/"},
+		Variables: []Variable{
+			{Name: "database", Type: VariableTypeString, Default: "postgres"},
+			{Name: "cache", Type: VariableTypeBool},
+		},
+		Exclude: []string{"docs"},
+	}
+	child := &Manifest{
+		Name: "Company",
+		Variables: []Variable{
+			{Name: "database", Type: VariableTypeString, Default: "mysql"},
+			{Name: "team", Type: VariableTypeString},
+		},
+	}
+
+	combined := parent.ExtendedBy(child)
+
+	if combined.Name != "Company" || len(combined.Structure) != 1 {
+		t.Errorf("unexpected metadata: %+v", combined)
+	}
+	if len(combined.Exclude) != 0 {
+		t.Errorf("exclude patterns should not be inherited, got %v", combined.Exclude)
+	}
+
+	names := make([]string, 0, len(combined.Variables))
+	for _, v := range combined.Variables {
+		names = append(names, v.Name)
+	}
+	if len(names) != 3 || names[0] != "database" || names[1] != "cache" || names[2] != "team" {
+		t.Errorf("unexpected variables: %v", names)
+	}
+	if combined.Variables[0].Default != "mysql" {
+		t.Errorf("expected the child default to win, got %v", combined.Variables[0].Default)
+	}
+}
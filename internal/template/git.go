@@ -0,0 +1,210 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// gitBackend performs the git operations on template repositories and their cached clones
+type gitBackend interface {
+	// clone clones a repository into dir without checking out any files.
+	// The token is not stored in the clone, fetches pass it again.
+	clone(repository, token, dir string, progress io.Writer) error
+	// fetch fetches the branches and tags of a repository into the clone in dir,
+	// pruning branches deleted on the remote
+	fetch(dir, repository, token string) error
+	// lsRemote lists the refs of a repository without cloning it, keyed by ref name.
+	// Annotated tags are listed a second time with a ^{} suffix, peeled to their commit.
+	lsRemote(repository, token string) (map[string]string, error)
+	// revParse returns the commit a ref, tag or (abbreviated) commit SHA resolves to in the clone in dir
+	revParse(dir, rev string) (string, error)
+	// checkout checks out a commit in the clone in dir, discarding local changes and untracked files
+	checkout(dir, commit string) error
+	// tags lists the tag names of the clone in dir
+	tags(dir string) ([]string, error)
+}
+
+// newGitBackend returns the git backend selected by the git_backend setting
+func newGitBackend(name string) gitBackend {
+	if name == config.GitBackendExec {
+		return execGit{}
+	}
+	return newGoGit()
+}
+
+// runGit runs a git command in dir and returns its trimmed output.
+// The error includes what git printed to stderr.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// hasCommit reports whether the clone in dir contains a commit
+func hasCommit(git gitBackend, dir, commit string) bool {
+	_, err := git.revParse(dir, commit)
+	return err == nil
+}
+
+// namedRef returns the commit the branch or tag named ref points to in the clone in dir.
+// Branches are read from the remote-tracking refs so that fetched commits are seen.
+func namedRef(git gitBackend, dir, ref string) (string, bool) {
+	for _, candidate := range []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref} {
+		if commit, err := git.revParse(dir, candidate); err == nil {
+			return commit, true
+		}
+	}
+	return "", false
+}
+
+// resolveRef returns the commit a branch, tag or commit SHA points to in the clone in dir.
+// Branches and tags named like a commit SHA take precedence over the commit.
+func resolveRef(git gitBackend, dir, ref string) (string, error) {
+	if commit, ok := namedRef(git, dir, ref); ok {
+		return commit, nil
+	}
+	if isCommitSHA(ref) {
+		if commit, err := git.revParse(dir, ref); err == nil {
+			return commit, nil
+		}
+	}
+
+	return "", fmt.Errorf("ref %s not found in repository", ref)
+}
+
+// checkoutRef checks out ref in the clone in dir, discarding local changes and untracked files,
+// and returns the checked out commit
+func checkoutRef(git gitBackend, dir, ref string) (string, error) {
+	commit, err := resolveRef(git, dir, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if err := git.checkout(dir, commit); err != nil {
+		return "", fmt.Errorf("failed to check out %s: %w", ref, err)
+	}
+
+	return commit, nil
+}
+
+// ShortCommit abbreviates a commit SHA for display
+func ShortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	if commit == "" {
+		return "unknown commit"
+	}
+	return commit
+}
+
+// authenticatedURL creates a GitHub URL with token authentication for the git binary
+func authenticatedURL(repoURL string, token string) string {
+	// Parse the URL to insert the token
+	// Format: https://TOKEN@github.com/user/repo.git
+
+	// Only HTTPS remotes carry the token, SSH and local repositories are used as is
+	if token == "" || !strings.HasPrefix(repoURL, "https://") && !strings.HasPrefix(repoURL, "http://") {
+		return repoURL
+	}
+
+	// Remove https:// prefix if present
+	url := strings.TrimPrefix(repoURL, "https://")
+	url = strings.TrimPrefix(url, "http://")
+
+	// Build authenticated URL
+	return fmt.Sprintf("https://%s@%s", token, url)
+}
+
+// execGit runs the git binary
+type execGit struct{}
+
+// clone implements gitBackend
+func (execGit) clone(repository, token, dir string, progress io.Writer) error {
+	cmd := exec.Command("git", "clone", "--no-checkout", authenticatedURL(repository, token), dir)
+	cmd.Stdout = progress
+	cmd.Stderr = progress
+
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	// Keep the token out of the cached git config, fetches pass it explicitly
+	if token != "" {
+		if _, err := runGit(dir, "remote", "set-url", "origin", repository); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch implements gitBackend
+func (execGit) fetch(dir, repository, token string) error {
+	_, err := runGit(dir, "fetch", "--quiet", "--tags", "--force", "--prune", authenticatedURL(repository, token),
+		"+refs/heads/*:refs/remotes/origin/*")
+	return err
+}
+
+// lsRemote implements gitBackend
+func (execGit) lsRemote(repository, token string) (map[string]string, error) {
+	out, err := exec.Command("git", "ls-remote", authenticatedURL(repository, token)).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+			return nil, fmt.Errorf("%s", bytes.TrimSpace(exitErr.Stderr))
+		}
+		return nil, err
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+
+	return refs, nil
+}
+
+// revParse implements gitBackend
+func (execGit) revParse(dir, rev string) (string, error) {
+	return runGit(dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+}
+
+// checkout implements gitBackend
+func (execGit) checkout(dir, commit string) error {
+	if _, err := runGit(dir, "checkout", "--quiet", "--force", "--detach", commit); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "clean", "--quiet", "-d", "--force", "-x"); err != nil {
+		return fmt.Errorf("failed to clean working tree: %w", err)
+	}
+	return nil
+}
+
+// tags implements gitBackend
+func (execGit) tags(dir string) ([]string, error) {
+	out, err := runGit(dir, "tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
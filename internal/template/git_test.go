@@ -0,0 +1,273 @@
+package template
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PickHD/pick-your-go/internal/cache"
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// newTestManager returns a manager with an empty cache and configuration, isolated from the user's
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	t.Setenv(cache.EnvCacheDir, t.TempDir())
+	t.Setenv(cache.EnvSystemCacheDirs, "")
+	t.Setenv(config.EnvConfigDir, t.TempDir())
+	t.Setenv(EnvLockFile, filepath.Join(t.TempDir(), LockFileName))
+
+	return &Manager{cacheManager: cache.NewManager()}
+}
+
+// initTestRepo creates a git repository with the given files committed on main
+func initTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "--quiet", "--initial-branch", "main")
+	commitTestRepo(t, dir, files)
+	return dir
+}
+
+// commitTestRepo writes files into a test repository and commits them
+func commitTestRepo(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	writeFiles(t, dir, files)
+	gitRun(t, dir, "add", "--all")
+	gitRun(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--message", "update")
+}
+
+// gitRun runs a git command in a test repository
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGit(dir, args...)
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+	return out
+}
+
+// TestPullTemplate tests updating a cached clone with each git backend
+func TestPullTemplate(t *testing.T) {
+	backends := map[string]gitBackend{"go": newGoGit(), "exec": execGit{}}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo := initTestRepo(t, map[string]string{"main.go": "package main // one\n"})
+			gitRun(t, repo, "tag", "v1.0.0")
+
+			manager := &Manager{git: backend}
+			tmpl := &Template{Repository: repo, Branch: "main"}
+			cachePath := filepath.Join(t.TempDir(), "demo")
+
+			first, err := manager.cloneTemplate(tmpl, cachePath, "")
+			if err != nil {
+				t.Fatalf("cloneTemplate failed: %v", err)
+			}
+
+			commitTestRepo(t, repo, map[string]string{"main.go": "package main // two\n"})
+			// Leftovers in the cache must not survive an update
+			writeFiles(t, cachePath, map[string]string{"junk.txt": "junk\n"})
+
+			second, err := manager.pullTemplate(tmpl, cachePath, "")
+			if err != nil {
+				t.Fatalf("pullTemplate failed: %v", err)
+			}
+			if second == first || second != gitRun(t, repo, "rev-parse", "HEAD") {
+				t.Errorf("expected the new commit to be checked out, got %s", second)
+			}
+			if data, _ := os.ReadFile(filepath.Join(cachePath, "main.go")); string(data) != "package main // two\n" {
+				t.Errorf("main.go = %q after update", data)
+			}
+			if _, err := os.Stat(filepath.Join(cachePath, "junk.txt")); !os.IsNotExist(err) {
+				t.Errorf("expected untracked files to be removed")
+			}
+			if remote, err := manager.remoteCommit(repo, "main", ""); err != nil || remote != second {
+				t.Errorf("expected the remote commit %s, got %s (%v)", second, remote, err)
+			}
+
+			// Tags and older commits come from the kept history
+			tagged, err := manager.pullTemplate(&Template{Repository: repo, Branch: "v1.0.0"}, cachePath, "")
+			if err != nil || tagged != first {
+				t.Errorf("expected the tag to check out %s, got %s (%v)", first, tagged, err)
+			}
+		})
+	}
+}
+
+// TestFetchPrunesDeletedBranches tests that the go backend drops the remote-tracking refs
+// of branches deleted upstream
+func TestFetchPrunesDeletedBranches(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main\n"})
+	gitRun(t, repo, "branch", "old")
+
+	backend := newGoGit()
+	dir := filepath.Join(t.TempDir(), "demo")
+	if err := backend.clone(repo, "", dir, &bytes.Buffer{}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	if _, ok := namedRef(backend, dir, "old"); !ok {
+		t.Fatalf("expected the clone to know the old branch")
+	}
+
+	gitRun(t, repo, "branch", "--delete", "old")
+	if err := backend.fetch(dir, repo, ""); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if _, ok := namedRef(backend, dir, "old"); ok {
+		t.Errorf("expected the deleted branch to be pruned")
+	}
+	if _, ok := namedRef(backend, dir, "main"); !ok {
+		t.Errorf("expected the main branch to be kept")
+	}
+}
+
+// TestPullTemplateWithoutGitDir tests that entries cached without history are reported for re-cloning
+func TestPullTemplateWithoutGitDir(t *testing.T) {
+	cachePath := t.TempDir()
+	writeFiles(t, cachePath, map[string]string{"main.go": "package main\n"})
+
+	if _, err := (&Manager{}).pullTemplate(&Template{Repository: "https://github.com/example/demo", Branch: "main"}, cachePath, ""); err == nil {
+		t.Errorf("expected an error for a cache entry without a git directory")
+	}
+}
+
+// TestConcurrentUpdates tests that clones of a manager update templates concurrently
+func TestConcurrentUpdates(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main\n"})
+	gitRun(t, repo, "tag", "v1.0.0")
+
+	manager := newTestManager(t)
+	manager.templates = []*Template{
+		{Type: "demo", Name: "demo", Repository: repo, Branch: "main"},
+		{Type: "pinned", Name: "pinned", Repository: repo, Branch: "v1.0.0"},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(archType config.ArchitectureType) {
+			defer wg.Done()
+			var output bytes.Buffer
+			worker := manager.Clone()
+			worker.SetOutput(&output)
+			errs <- worker.UpdateTemplate(archType, "")
+		}([]config.ArchitectureType{"demo", "pinned"}[i%2])
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("UpdateTemplate failed: %v", err)
+		}
+	}
+	for _, archType := range []config.ArchitectureType{"demo", "pinned"} {
+		if !manager.IsCached(archType) {
+			t.Errorf("expected %s to be cached", archType)
+		}
+	}
+}
+
+// TestUpdateWaitsForReaders tests that an update does not move a cache entry to a new commit
+// while another run reads it
+func TestUpdateWaitsForReaders(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main // one\n"})
+
+	manager := newTestManager(t)
+	manager.templates = []*Template{{Type: "demo", Name: "demo", Repository: repo, Branch: "main"}}
+	manager.SetOutput(&bytes.Buffer{})
+	if err := manager.EnsureTemplateCached("demo", ""); err != nil {
+		t.Fatalf("EnsureTemplateCached failed: %v", err)
+	}
+
+	// Separate managers behave like separate runs
+	reader := manager.Clone()
+	release := reader.Hold()
+	path, err := reader.GetTemplatePath("demo")
+	if err != nil {
+		t.Fatalf("GetTemplatePath failed: %v", err)
+	}
+
+	commitTestRepo(t, repo, map[string]string{"main.go": "package main // two\n"})
+	updated := make(chan error)
+	go func() {
+		updated <- manager.Clone().UpdateTemplate("demo", "")
+	}()
+
+	select {
+	case err := <-updated:
+		release()
+		t.Fatalf("expected the update to wait for the reader, got %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if data, _ := os.ReadFile(filepath.Join(path, "main.go")); string(data) != "package main // one\n" {
+		t.Errorf("expected the entry to be unchanged while read, got %q", data)
+	}
+	release()
+
+	if err := <-updated; err != nil {
+		t.Fatalf("UpdateTemplate failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(path, "main.go")); string(data) != "package main // two\n" {
+		t.Errorf("expected the entry to be updated once read, got %q", data)
+	}
+}
+
+// newArchivingTestManager returns a test manager storing cache entries as gzip archives
+// and serving a demo template from repo
+func newArchivingTestManager(t *testing.T, repo string) *Manager {
+	t.Helper()
+	manager := newTestManager(t)
+	settings := filepath.Join(os.Getenv(config.EnvConfigDir), config.SettingsFileName)
+	if err := os.WriteFile(settings, []byte("cache_compression: gzip\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manager.cacheManager = cache.NewManager()
+	manager.templates = []*Template{{Type: "demo", Name: "demo", Repository: repo, Branch: "main"}}
+	manager.SetOutput(&bytes.Buffer{})
+	return manager
+}
+
+// TestArchivedTemplateExtraction tests that archived templates are extracted only while held
+func TestArchivedTemplateExtraction(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main\n"})
+	manager := newArchivingTestManager(t, repo)
+
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+	if err := manager.EnsureTemplateVersionCached("demo", "", ""); err != nil {
+		t.Fatalf("EnsureTemplateVersionCached failed: %v", err)
+	}
+
+	release := manager.Hold()
+	path, err := manager.GetTemplatePath("demo")
+	if err != nil {
+		t.Fatalf("GetTemplatePath failed: %v", err)
+	}
+	if filepath.Dir(path) != tmpDir {
+		t.Fatalf("expected the archived template to be extracted, got %s", path)
+	}
+	if again, _ := manager.GetTemplatePath("demo"); again != path {
+		t.Errorf("expected the template to be extracted once per hold, got %s and %s", path, again)
+	}
+	if err := manager.CopyTemplateToDestination("demo", filepath.Join(t.TempDir(), "project"), RenderData{}); err != nil {
+		t.Errorf("CopyTemplateToDestination failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "main.go")); err != nil {
+		t.Errorf("expected the template to stay extracted while held: %v", err)
+	}
+	release()
+
+	if leftovers, _ := os.ReadDir(tmpDir); len(leftovers) != 0 {
+		t.Errorf("expected extracted templates to be removed, got %d directories", len(leftovers))
+	}
+}
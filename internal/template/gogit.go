@@ -0,0 +1,236 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// installFileTransport replaces the file transport of go-git, which runs git-upload-pack,
+// so that local repositories are served in-process. It is installed once the go backend is
+// used, the transport is shared by the whole process.
+var installFileTransport = sync.OnceFunc(func() {
+	client.InstallProtocol("file", server.NewClient(localLoader{}))
+})
+
+// localLoader loads local repositories for the in-process file transport, with or without a working tree
+type localLoader struct{}
+
+// Load implements server.Loader
+func (localLoader) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	for _, dir := range []string{filepath.Join(ep.Path, ".git"), ep.Path} {
+		if _, err := os.Stat(filepath.Join(dir, "config")); err == nil {
+			return filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault()), nil
+		}
+	}
+	return nil, transport.ErrRepositoryNotFound
+}
+
+// goGit runs git operations in-process with go-git, no git binary is needed
+type goGit struct{}
+
+// newGoGit returns the go-git backend, installing the in-process file transport it relies on
+func newGoGit() goGit {
+	installFileTransport()
+	return goGit{}
+}
+
+// tokenAuth returns the credentials for a repository, only HTTPS remotes carry the token
+func tokenAuth(repository, token string) transport.AuthMethod {
+	if token == "" || !strings.HasPrefix(repository, "https://") && !strings.HasPrefix(repository, "http://") {
+		return nil
+	}
+	return &http.BasicAuth{Username: "x-access-token", Password: token}
+}
+
+// clone implements gitBackend
+func (goGit) clone(repository, token, dir string, progress io.Writer) error {
+	_, err := git.PlainClone(dir, false, &git.CloneOptions{
+		URL:        repository,
+		Auth:       tokenAuth(repository, token),
+		NoCheckout: true,
+		Progress:   progress,
+		Tags:       git.AllTags,
+	})
+	return err
+}
+
+// fetch implements gitBackend
+func (g goGit) fetch(dir, repository, token string) error {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+
+	// go-git does not prune, the branches listed up front tell which remote-tracking refs are
+	// stale; without the listing the fetch still goes ahead and pruning waits for the next one
+	remote, listErr := g.lsRemote(repository, token)
+
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteURL: repository,
+		RefSpecs: []gitconfig.RefSpec{
+			"+refs/heads/*:refs/remotes/origin/*",
+			"+refs/tags/*:refs/tags/*",
+		},
+		Auth:  tokenAuth(repository, token),
+		Tags:  git.AllTags,
+		Force: true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+	if listErr != nil {
+		return nil
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return err
+	}
+	return refs.ForEach(func(ref *plumbing.Reference) error {
+		branch, ok := strings.CutPrefix(ref.Name().String(), "refs/remotes/origin/")
+		if !ok {
+			return nil
+		}
+		if _, exists := remote["refs/heads/"+branch]; exists {
+			return nil
+		}
+		return repo.Storer.RemoveReference(ref.Name())
+	})
+}
+
+// lsRemote implements gitBackend
+func (goGit) lsRemote(repository, token string) (map[string]string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repository},
+	})
+
+	list, err := remote.List(&git.ListOptions{
+		Auth:          tokenAuth(repository, token),
+		PeelingOption: git.AppendPeeled,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]string, len(list))
+	for _, ref := range list {
+		if ref.Type() == plumbing.HashReference {
+			refs[ref.Name().String()] = ref.Hash().String()
+		}
+	}
+
+	return refs, nil
+}
+
+// revParse implements gitBackend
+func (goGit) revParse(dir, rev string) (string, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// Tags are peeled to the commit they point to
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", rev, err)
+	}
+	return hash.String(), nil
+}
+
+// checkout implements gitBackend
+func (goGit) checkout(dir, commit string) error {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(commit), Force: true}); err != nil {
+		return err
+	}
+
+	// Like git clean -dfx, ignored files included
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	tracked := make(map[string]bool, len(index.Entries))
+	for _, entry := range index.Entries {
+		tracked[entry.Name] = true
+		for parent := path.Dir(entry.Name); parent != "."; parent = path.Dir(parent) {
+			tracked[parent] = true
+		}
+	}
+
+	return filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == dir {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == ".git" {
+			return filepath.SkipDir
+		}
+		if tracked[relPath] {
+			return nil
+		}
+
+		if err := os.RemoveAll(filePath); err != nil {
+			return fmt.Errorf("failed to clean working tree: %w", err)
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// tags implements gitBackend
+func (goGit) tags(dir string) ([]string, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		tags = append(tags, ref.Name().Short())
+		return nil
+	})
+	return tags, err
+}
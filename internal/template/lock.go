@@ -0,0 +1,363 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/PickHD/pick-your-go/internal/cache"
+	"github.com/PickHD/pick-your-go/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// LockFileName is the name of the lock file looked up in the current directory
+	LockFileName = "pick-your-go.lock"
+	// EnvLockFile overrides the location of the lock file
+	EnvLockFile = "PICK_YOUR_GO_LOCK_FILE"
+)
+
+// ErrLockMismatch is returned when fetched template files do not match the locked content hash
+var ErrLockMismatch = errors.New("template does not match the lock file")
+
+// LockFile pins template sources to exact commits and content hashes
+type LockFile struct {
+	// Templates are the locked template sources
+	Templates []LockEntry `yaml:"templates"`
+
+	path string
+}
+
+// LockEntry pins one template source
+type LockEntry struct {
+	// Repository is the template repository URL
+	Repository string `yaml:"repository"`
+	// Ref is the branch, tag or range the commit was resolved from
+	Ref string `yaml:"ref"`
+	// Commit is the locked commit
+	Commit string `yaml:"commit"`
+	// Hash is the content hash of the template files at the commit
+	Hash string `yaml:"hash"`
+}
+
+// GetLockFilePath returns the path of the lock file
+func GetLockFilePath() (string, error) {
+	if path := os.Getenv(EnvLockFile); path != "" {
+		return path, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	return filepath.Join(wd, LockFileName), nil
+}
+
+// LoadLockFile reads a lock file.
+// A missing file is not an error and yields an empty lock.
+func LoadLockFile(path string) (*LockFile, error) {
+	lock := &LockFile{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lock, nil
+		}
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+
+	for i, entry := range lock.Templates {
+		if entry.Repository == "" || entry.Commit == "" || entry.Hash == "" {
+			return nil, fmt.Errorf("invalid lock file %s: entry #%d needs repository, commit and hash", path, i+1)
+		}
+		if !isFullCommitSHA(entry.Commit) {
+			return nil, fmt.Errorf("invalid lock file %s: %q is not a full commit SHA", path, entry.Commit)
+		}
+	}
+
+	return lock, nil
+}
+
+// Find returns the entry locking a repository, or nil
+func (l *LockFile) Find(repository string) *LockEntry {
+	for i := range l.Templates {
+		if l.Templates[i].Repository == repository {
+			return &l.Templates[i]
+		}
+	}
+	return nil
+}
+
+// Set adds an entry or replaces the entry of the same repository
+func (l *LockFile) Set(entry LockEntry) {
+	if existing := l.Find(entry.Repository); existing != nil {
+		*existing = entry
+		return
+	}
+	l.Templates = append(l.Templates, entry)
+}
+
+// Path returns the location of the lock file
+func (l *LockFile) Path() string {
+	return l.path
+}
+
+// Save writes the lock file to disk
+func (l *LockFile) Save() error {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by pick-your-go templates lock. Commit this file to pin template sources.\n")
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(l); err != nil {
+		return fmt.Errorf("failed to marshal lock file: %w", err)
+	}
+
+	if err := os.WriteFile(l.path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	return nil
+}
+
+// getLock returns the lock file of the current directory, loading it on first use
+func (m *Manager) getLock() (*LockFile, error) {
+	if m.lock != nil {
+		return m.lock, nil
+	}
+
+	path, err := GetLockFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := LoadLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m.lock = lock
+
+	return lock, nil
+}
+
+// lockEntry returns the lock entry of a repository, or nil when it is not locked
+func (m *Manager) lockEntry(repository string) (*LockEntry, error) {
+	lock, err := m.getLock()
+	if err != nil {
+		return nil, err
+	}
+	return lock.Find(repository), nil
+}
+
+// verifyLocked checks the cached files of a template against the locked content hash.
+// A mismatching cache entry is removed so that it is never used.
+func (m *Manager) verifyLocked(key string, entry *LockEntry) error {
+	hash, err := m.cacheManager.EntryHash(key)
+	if err != nil {
+		return err
+	}
+
+	if hash != entry.Hash {
+		if err := m.cacheManager.ClearTemplateCache(key); err != nil {
+			fmt.Fprintf(m.out(), "Warning: failed to remove mismatching cache entry: %v\n", err)
+		}
+		return fmt.Errorf("%w: %s at %s has content hash %s, %s expects %s",
+			ErrLockMismatch, entry.Repository, entry.Commit, hash, LockFileName, entry.Hash)
+	}
+
+	return nil
+}
+
+// ensureCached ensures a template is cached at its branch, or at the locked commit
+// when the lock file pins its repository, and returns its cache entry. It is downloaded
+// when it is missing, or when its refresh policy calls for a check and the branch moved upstream.
+// The entry is recorded as used for pruning.
+func (m *Manager) ensureCached(tmpl *Template, token string) (string, error) {
+	key, err := m.fetchCached(tmpl, token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.cacheManager.MarkUsed(key); err != nil {
+		fmt.Fprintf(m.out(), "Warning: failed to record the use of %s in the cache: %v\n", tmpl.Name, err)
+	}
+
+	return key, nil
+}
+
+// fetchCached downloads or refreshes a cache entry as described for ensureCached
+func (m *Manager) fetchCached(tmpl *Template, token string) (string, error) {
+	defer m.Hold()()
+
+	tmpl, entry, err := m.lockedTemplate(tmpl)
+	if err != nil {
+		return "", err
+	}
+	key := tmpl.cacheKey()
+
+	if !m.cacheManager.Exists(key) {
+		if m.offline {
+			return "", fmt.Errorf("%s is not cached at %s and cannot be downloaded in offline mode", tmpl.Name, tmpl.Branch)
+		}
+		return key, m.updateTemplate(tmpl, token)
+	}
+	if err := m.repairCorrupted(key, tmpl, token); err != nil {
+		return key, err
+	}
+	if entry != nil {
+		return key, m.verifyLocked(key, entry)
+	}
+	if m.offline || !m.cacheManager.IsCacheExpired(key) {
+		return key, nil
+	}
+
+	return key, m.refreshCached(key, tmpl, token)
+}
+
+// repairCorrupted downloads a cache entry again when its files no longer match the
+// file manifest recorded when it was downloaded, e.g. because they were edited or deleted
+func (m *Manager) repairCorrupted(key string, tmpl *Template, token string) error {
+	if err := m.holdEntry(key); err != nil {
+		return err
+	}
+
+	report, err := m.cacheManager.VerifyEntry(key)
+	if err != nil {
+		return err
+	}
+	if report.OK() {
+		return nil
+	}
+
+	// Entries cached before file manifests were recorded have nothing to compare with,
+	// they are updated once to record one
+	if report.NoManifest {
+		if m.offline {
+			return nil
+		}
+		return m.updateTemplate(tmpl, token)
+	}
+
+	if m.offline {
+		return fmt.Errorf("cached %s is corrupted (%s) and cannot be downloaded again in offline mode", tmpl.Name, report)
+	}
+
+	fmt.Fprintf(m.out(), "Warning: cached %s is corrupted (%s), downloading it again\n", tmpl.Name, report)
+	return m.updateTemplate(tmpl, token)
+}
+
+// LockTemplate resolves a template source to its current commit and content hash
+// and records it in the lock, together with the templates it extends.
+// The ref is resolved afresh, ignoring any existing lock entry, and the cache is left untouched.
+func (m *Manager) LockTemplate(lock *LockFile, tmpl *Template, token string) ([]LockEntry, error) {
+	tempDir, err := os.MkdirTemp("", "pick-your-go-lock-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	clonePath := filepath.Join(tempDir, "template")
+	commit, err := m.cloneTemplate(tmpl, clonePath, token)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := cache.HashDirectory(clonePath)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := LockEntry{
+		Repository: tmpl.Repository,
+		Ref:        tmpl.Branch,
+		Commit:     commit,
+		Hash:       hash,
+	}
+	lock.Set(entry)
+	locked := []LockEntry{entry}
+
+	// Lock the parent template as well, so the whole chain is reproducible
+	manifest, err := LoadManifest(filepath.Join(clonePath, filepath.FromSlash(tmpl.Subdir)))
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil && manifest.Extends != "" {
+		parent, err := m.resolveParent(clonePath, manifest.Extends)
+		if err != nil {
+			return nil, err
+		}
+		if parent.template != nil && parent.template.Repository != tmpl.Repository {
+			parents, err := m.LockTemplate(lock, parent.template, token)
+			if err != nil {
+				return nil, fmt.Errorf("failed to lock parent template %s: %w", manifest.Extends, err)
+			}
+			locked = append(locked, parents...)
+		}
+	}
+
+	return locked, nil
+}
+
+// LockTemplates records template sources in the lock.
+// Names select registry templates or overlays; without names every registry template and overlay
+// is considered. Sources already in the lock are kept unless update is set, in which case they are
+// resolved again from their ref. Updating without names re-resolves every entry of the lock.
+func (m *Manager) LockTemplates(lock *LockFile, names []string, update bool, token string) ([]LockEntry, error) {
+	var targets []*Template
+	switch {
+	case len(names) > 0:
+		for _, name := range names {
+			tmpl, err := m.getLockTarget(name)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, tmpl)
+		}
+	case update && len(lock.Templates) > 0:
+		for _, entry := range lock.Templates {
+			targets = append(targets, &Template{Name: entry.Repository, Repository: entry.Repository, Branch: entry.Ref})
+		}
+	default:
+		targets = append(targets, m.templates...)
+		for _, overlay := range m.overlays {
+			targets = append(targets, overlay.template())
+		}
+	}
+
+	var locked []LockEntry
+	for _, tmpl := range targets {
+		if !update && lock.Find(tmpl.Repository) != nil {
+			continue
+		}
+
+		fmt.Fprintf(m.out(), "Resolving %s@%s...\n", tmpl.Repository, tmpl.Branch)
+		entries, err := m.LockTemplate(lock, tmpl, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", tmpl.Name, err)
+		}
+		locked = append(locked, entries...)
+	}
+
+	return locked, nil
+}
+
+// getLockTarget returns the registry template or overlay with the given name
+func (m *Manager) getLockTarget(name string) (*Template, error) {
+	if tmpl, err := m.GetTemplate(config.ArchitectureType(name)); err == nil {
+		return tmpl, nil
+	}
+	for _, overlay := range m.overlays {
+		if overlay.Name == name {
+			return overlay.template(), nil
+		}
+	}
+	return nil, fmt.Errorf("template or overlay not found: %s", name)
+}
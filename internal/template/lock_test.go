@@ -0,0 +1,105 @@
+package template
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PickHD/pick-your-go/internal/cache"
+)
+
+// TestLockFileRoundTrip tests saving and loading a lock file
+func TestLockFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+
+	lock, err := LoadLockFile(path)
+	if err != nil {
+		t.Fatalf("LoadLockFile failed for a missing file: %v", err)
+	}
+	if len(lock.Templates) != 0 {
+		t.Errorf("expected an empty lock, got %v", lock.Templates)
+	}
+
+	lock.Set(LockEntry{Repository: "https://github.com/example/a", Ref: "main", Commit: strings.Repeat("1", 40), Hash: "sha256:aa"})
+	lock.Set(LockEntry{Repository: "https://github.com/example/b", Ref: "v1.0.0", Commit: strings.Repeat("2", 40), Hash: "sha256:bb"})
+	lock.Set(LockEntry{Repository: "https://github.com/example/a", Ref: "main", Commit: strings.Repeat("3", 40), Hash: "sha256:cc"})
+	if err := lock.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadLockFile(path)
+	if err != nil {
+		t.Fatalf("LoadLockFile failed: %v", err)
+	}
+	if len(loaded.Templates) != 2 {
+		t.Fatalf("expected 2 entries, got %v", loaded.Templates)
+	}
+	if entry := loaded.Find("https://github.com/example/a"); entry == nil || entry.Commit != strings.Repeat("3", 40) || entry.Hash != "sha256:cc" {
+		t.Errorf("expected the replaced entry, got %+v", entry)
+	}
+	if loaded.Find("https://github.com/example/c") != nil {
+		t.Errorf("expected no entry for an unlocked repository")
+	}
+}
+
+// TestLoadLockFileInvalid tests that incomplete lock entries are rejected
+func TestLoadLockFileInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing hash", "templates:\n  - repository: https://github.com/example/a\n    commit: 1111111\n"},
+		{"missing commit", "templates:\n  - repository: https://github.com/example/a\n    hash: sha256:aa\n"},
+		{"branch as commit", "templates:\n  - repository: https://github.com/example/a\n    commit: main\n    hash: sha256:aa\n"},
+		{"abbreviated commit", "templates:\n  - repository: https://github.com/example/a\n    commit: 1111111\n    hash: sha256:aa\n"},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), LockFileName)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadLockFile(path); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+// TestVerifyLocked tests that cached files are checked against the locked content hash
+func TestVerifyLocked(t *testing.T) {
+	manager := newTestManager(t)
+	key := (&Template{Repository: "https://github.com/example/docker", Branch: "1111111"}).cacheKey()
+	cachePath := manager.cacheManager.GetTemplateCachePath(key)
+	writeFiles(t, cachePath, map[string]string{
+		"Dockerfile":  "FROM golang\n",
+		".git/HEAD":   "ref: refs/heads/main\n",
+		"docs/how.md": "docs\n",
+	})
+
+	hash, err := cache.HashDirectory(cachePath)
+	if err != nil {
+		t.Fatalf("HashDirectory failed: %v", err)
+	}
+
+	entry := &LockEntry{Repository: "https://github.com/example/docker", Commit: strings.Repeat("1", 40), Hash: hash}
+	if err := manager.verifyLocked(key, entry); err != nil {
+		t.Errorf("expected matching files to verify, got %v", err)
+	}
+
+	// Git metadata is not part of the template files
+	writeFiles(t, cachePath, map[string]string{".git/ORIG_HEAD": "x\n"})
+	if err := manager.verifyLocked(key, entry); err != nil {
+		t.Errorf("expected .git to be ignored, got %v", err)
+	}
+
+	writeFiles(t, cachePath, map[string]string{"Dockerfile": "FROM alpine\n"})
+	err = manager.verifyLocked(key, entry)
+	if !errors.Is(err, ErrLockMismatch) {
+		t.Errorf("expected ErrLockMismatch for modified files, got %v", err)
+	}
+	if _, statErr := os.Stat(cachePath); !os.IsNotExist(statErr) {
+		t.Errorf("expected the mismatching cache entry to be removed")
+	}
+}
@@ -3,10 +3,13 @@ package template
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/PickHD/pick-your-go/internal/cache"
 	"github.com/PickHD/pick-your-go/internal/config"
@@ -14,28 +17,134 @@ import (
 
 // Template represents a project template
 type Template struct {
-	Type        config.ArchitectureType `json:"type"`
-	Name        string                  `json:"name"`
-	Description string                  `json:"description"`
-	Repository  string                  `json:"repository"`
-	Branch      string                  `json:"branch"`
+	Type        config.ArchitectureType `json:"type" yaml:"type"`
+	Name        string                  `json:"name" yaml:"name"`
+	Description string                  `json:"description" yaml:"description"`
+	Repository  string                  `json:"repository" yaml:"repository"`
+	Branch      string                  `json:"branch" yaml:"branch"`
+	// Subdir is the template directory inside the repository, empty for the repository root
+	Subdir string `json:"subdir,omitempty" yaml:"subdir,omitempty"`
+	// Refresh is the refresh policy of the cached template: never, always or a duration
+	Refresh string `json:"refresh,omitempty" yaml:"refresh,omitempty"`
 }
 
 // Manager handles template operations
 type Manager struct {
 	cacheManager *cache.Manager
 	templates    []*Template
+	overlays     []*Overlay
+	lock         *LockFile
+	offline      bool
+	// resolved maps templates to the cache entry of the version requested in this run
+	resolved map[config.ArchitectureType]string
+	// output receives download progress and git output, stdout when unset
+	output io.Writer
+	// git clones and fetches template repositories, see the git_backend setting
+	git gitBackend
+	// refreshed holds the cache entries checked against their remote, shared with clones
+	refreshed *sync.Map
+	// holds counts the nested calls of Hold. Until the outermost hold is released, readLocks
+	// keeps the cache entries read from locked and extracted maps archived entries to the
+	// directories they are extracted to.
+	holds     int
+	readLocks map[string]func()
+	extracted map[string]string
+	releases  []func()
 }
 
-// NewManager creates a new template manager
+// NewManager creates a new template manager.
+// Templates declared in the user registry file are merged with the defaults, and
+// offline mode is enabled when PICK_YOUR_GO_OFFLINE is set.
 func NewManager() *Manager {
+	templates, overlays, err := loadTemplates()
+	if err != nil {
+		// Fall back to the default templates, a broken registry should not block generation
+		fmt.Printf("Warning: failed to load template registry: %v\n", err)
+	}
+
+	// Broken settings are reported where the settings are loaded for hooks
+	settings, err := config.LoadSettings()
+	if err != nil {
+		settings = &config.Settings{}
+	}
+
 	m := &Manager{
 		cacheManager: cache.NewManager(),
-		templates:    getDefaultTemplates(),
+		templates:    templates,
+		overlays:     overlays,
+		offline:      config.IsOffline(),
+		git:          newGitBackend(settings.GitBackend),
 	}
+	m.applyRefreshPolicies()
+
 	return m
 }
 
+// Clone returns a manager of the same registry with its own cache state; the record of
+// the entries checked against their remote is shared. Managers are not safe for concurrent
+// use, concurrent updates use a clone each.
+func (m *Manager) Clone() *Manager {
+	clone := &Manager{
+		cacheManager: cache.NewManager(),
+		templates:    m.templates,
+		overlays:     m.overlays,
+		offline:      m.offline,
+		output:       m.output,
+		git:          m.git,
+		refreshed:    m.refreshedEntries(),
+	}
+	clone.applyRefreshPolicies()
+
+	return clone
+}
+
+// applyRefreshPolicies sets the refresh policies declared by registry templates and overlays.
+// Templates without a refresh policy use the default from the settings.
+func (m *Manager) applyRefreshPolicies() {
+	for _, tmpl := range m.templates {
+		if policy, err := config.ParseRefreshPolicy(tmpl.Refresh); err == nil && tmpl.Refresh != "" {
+			m.cacheManager.SetRefreshPolicy(string(tmpl.Type), policy)
+		}
+	}
+	for _, overlay := range m.overlays {
+		if policy, err := config.ParseRefreshPolicy(overlay.Refresh); err == nil && overlay.Refresh != "" {
+			m.cacheManager.SetRefreshPolicy(string(overlay.template().Type), policy)
+		}
+	}
+}
+
+// SetOutput sets where download progress and git output are written, stdout by default
+func (m *Manager) SetOutput(w io.Writer) {
+	m.output = w
+}
+
+// gitBackend returns the git backend of the manager, in-process git unless configured otherwise
+func (m *Manager) gitBackend() gitBackend {
+	if m.git == nil {
+		return newGoGit()
+	}
+	return m.git
+}
+
+// out returns the writer for download progress
+func (m *Manager) out() io.Writer {
+	if m.output == nil {
+		return os.Stdout
+	}
+	return m.output
+}
+
+// SetOffline switches offline mode on or off.
+// In offline mode cached templates are used regardless of age and nothing is downloaded.
+func (m *Manager) SetOffline(offline bool) {
+	m.offline = offline
+}
+
+// IsOffline reports whether the manager is in offline mode
+func (m *Manager) IsOffline() bool {
+	return m.offline
+}
+
 // getDefaultTemplates returns the default template definitions
 func getDefaultTemplates() []*Template {
 	return []*Template{
@@ -78,18 +187,97 @@ func (m *Manager) GetTemplate(archType config.ArchitectureType) (*Template, erro
 	return nil, fmt.Errorf("template not found for architecture type: %s", archType)
 }
 
+// GetManifest returns the manifest for a template.
+// The manifest shipped in the cached template takes precedence over the built-in defaults.
+func (m *Manager) GetManifest(archType config.ArchitectureType) (*Manifest, error) {
+	defer m.Hold()()
+
+	if templatePath, err := m.GetTemplatePath(archType); err == nil {
+		manifest, err := m.loadChainManifest(templatePath)
+		if err != nil {
+			return nil, err
+		}
+		if manifest != nil {
+			return m.withTemplateDefaults(manifest, archType), nil
+		}
+	}
+
+	return m.withTemplateDefaults(getDefaultManifest(archType), archType), nil
+}
+
+// GetDirectoryManifest returns the manifest for a local template directory
+func (m *Manager) GetDirectoryManifest(templateDir string, archType config.ArchitectureType) (*Manifest, error) {
+	defer m.Hold()()
+
+	manifest, err := m.loadChainManifest(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		manifest = getDefaultManifest(archType)
+	}
+
+	return m.withTemplateDefaults(manifest, archType), nil
+}
+
+// ResolveManifest returns the manifest of the template that will be used for generation.
+// Remote templates are downloaded first, at the requested version, so that their manifest is available.
+func (m *Manager) ResolveManifest(archType config.ArchitectureType, templateDir, version, token string) (*Manifest, error) {
+	if templateDir != "" {
+		if err := m.EnsureParentsCached(templateDir, token); err != nil {
+			return nil, err
+		}
+		return m.GetDirectoryManifest(templateDir, archType)
+	}
+
+	if err := m.EnsureTemplateVersionCached(archType, version, token); err != nil {
+		return nil, fmt.Errorf("failed to ensure template is cached: %w", err)
+	}
+
+	return m.GetManifest(archType)
+}
+
+// withTemplateDefaults fills missing manifest metadata from the template definition
+func (m *Manager) withTemplateDefaults(manifest *Manifest, archType config.ArchitectureType) *Manifest {
+	if tmpl, err := m.GetTemplate(archType); err == nil {
+		if manifest.Name == "" {
+			manifest.Name = tmpl.Name
+		}
+		if manifest.Description == "" {
+			manifest.Description = tmpl.Description
+		}
+	}
+
+	if manifest.Name == "" {
+		manifest.Name = archType.DisplayName()
+	}
+	if manifest.Description == "" {
+		manifest.Description = archType.Description()
+	}
+
+	return manifest
+}
+
 // IsCached checks if a template is cached
 func (m *Manager) IsCached(archType config.ArchitectureType) bool {
-	return m.cacheManager.IsCached(archType)
+	key, _, err := m.templateEntry(archType)
+	return err == nil && m.cacheManager.Exists(key)
 }
 
-// GetTemplatePath returns the path to a cached template
+// GetTemplatePath returns the path to a cached template.
+// Archived templates are extracted until the enclosing Hold is released.
 func (m *Manager) GetTemplatePath(archType config.ArchitectureType) (string, error) {
-	if !m.IsCached(archType) {
+	defer m.Hold()()
+
+	key, tmpl, err := m.templateEntry(archType)
+	if err != nil {
+		return "", err
+	}
+	if !m.cacheManager.Exists(key) {
 		return "", fmt.Errorf("template not cached: %s", archType)
 	}
 
-	return m.cacheManager.GetTemplateCachePath(archType), nil
+	return m.entryDir(key, tmpl)
 }
 
 // UpdateTemplate downloads or updates a template from GitHub
@@ -99,97 +287,186 @@ func (m *Manager) UpdateTemplate(archType config.ArchitectureType, token string)
 		return fmt.Errorf("failed to get template: %w", err)
 	}
 
-	cachePath := m.cacheManager.GetTemplateCachePath(archType)
+	return m.updateTemplate(template, token)
+}
+
+// updateTemplate downloads or updates a template repository into the cache entry of its source.
+// The template Branch may hold any ref: a branch, a tag or a commit SHA.
+func (m *Manager) updateTemplate(template *Template, token string) error {
+	// A locked repository is always fetched at the locked commit
+	template, entry, err := m.lockedTemplate(template)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		fmt.Fprintf(m.out(), "Using locked commit %s of %s\n", entry.Commit, template.Repository)
+	}
+
+	key := template.cacheKey()
+	cachePath := m.cacheManager.GetTemplateCachePath(key)
+
+	if m.offline {
+		return fmt.Errorf("cannot download %s in offline mode", template.Repository)
+	}
+
+	// Concurrent runs wait for each other and for readers instead of writing into the entry
+	// while it is read; the read lock of this manager is dropped so as not to wait for itself
+	m.releaseEntry(key)
+	unlock, err := m.cacheManager.LockEntry(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
+	var commit string
 	// Check if template directory already exists
 	if _, err := os.Stat(cachePath); err == nil {
-		// Directory exists, pull latest changes
-		if err := m.pullTemplate(cachePath, token); err != nil {
-			// If pull fails, try cloning fresh
-			if err := os.RemoveAll(cachePath); err != nil {
-				return fmt.Errorf("failed to remove old cache: %w", err)
-			}
-			if err := m.cloneTemplate(template, cachePath, token); err != nil {
+		// Directory exists, fetch the latest changes
+		if commit, err = m.pullTemplate(template, cachePath, token); err != nil {
+			// If pull fails, try cloning fresh, keeping the old copy until the clone succeeded
+			if commit, err = m.installTemplate(template, cachePath, token); err != nil {
 				return err
 			}
 		}
 	} else {
 		// Directory doesn't exist, clone it
-		if err := m.cloneTemplate(template, cachePath, token); err != nil {
+		if info, err := m.cacheManager.GetCacheInfo(key); err == nil && info.Archive != "" {
+			fmt.Fprintf(m.out(), "Downloading %s again, compressed cache entries keep no git history to fetch into\n", template.Name)
+		}
+		if commit, err = m.installTemplate(template, cachePath, token); err != nil {
+			return err
+		}
+	}
+
+	// Record the files as checked out, later changes in the cache are detected and repaired
+	if err := cache.WriteFileManifest(cachePath); err != nil {
+		return err
+	}
+
+	if entry != nil {
+		if err := m.verifyLocked(key, entry); err != nil {
 			return err
 		}
 	}
 
 	// Update cache metadata AFTER successful clone/pull
-	return m.cacheManager.UpdateCacheTime(archType)
+	if err := m.cacheManager.UpdateCacheSource(string(template.Type), template.source(), commit); err != nil {
+		return err
+	}
+
+	return m.cacheManager.CompressEntry(key)
 }
 
-// cloneTemplate clones a template repository from GitHub
-func (m *Manager) cloneTemplate(template *Template, cachePath string, token string) error {
+// cloneTemplate clones a template repository into the cache and checks out its ref.
+// The git directory is kept so that later updates only fetch new commits.
+func (m *Manager) cloneTemplate(template *Template, cachePath string, token string) (string, error) {
 	// Ensure parent directory exists
 	parentDir := filepath.Dir(cachePath)
 	if err := os.MkdirAll(parentDir, 0755); err != nil {
-		return fmt.Errorf("failed to create cache directory: %w", err)
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
-	// Build git clone command with token authentication
-	repoURL := m.buildAuthenticatedURL(template.Repository, token)
+	git := m.gitBackend()
+	if err := git.clone(template.Repository, token, cachePath, m.out()); err != nil {
+		return "", fmt.Errorf("failed to clone repository: %w", err)
+	}
 
-	cmd := exec.Command("git", "clone", "--depth", "1", "--branch", template.Branch, repoURL, cachePath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	return checkoutRef(git, cachePath, template.Branch)
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to clone repository: %w", err)
+// installTemplate clones a template into a temporary directory next to the cache entry
+// and renames it into place, so that an interrupted clone never shows up as a cache entry.
+// An existing entry is only replaced once the clone succeeded.
+func (m *Manager) installTemplate(template *Template, cachePath string, token string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
-	// Remove .git directory to save space
-	gitDir := filepath.Join(cachePath, ".git")
-	if err := os.RemoveAll(gitDir); err != nil {
-		// Not a critical error, just log it
-		fmt.Printf("Warning: failed to remove .git directory: %v\n", err)
+	tempDir, err := os.MkdirTemp(filepath.Dir(cachePath), ".clone-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
 	}
+	defer os.RemoveAll(tempDir)
 
-	return nil
-}
+	clonePath := filepath.Join(tempDir, filepath.Base(cachePath))
+	commit, err := m.cloneTemplate(template, clonePath, token)
+	if err != nil {
+		return "", err
+	}
+
+	// Move the old entry aside instead of deleting it first, so the entry is never missing
+	// for longer than two renames; it is removed together with the temporary directory
+	if _, err := os.Stat(cachePath); err == nil {
+		if err := os.Rename(cachePath, filepath.Join(tempDir, "old")); err != nil {
+			return "", fmt.Errorf("failed to move old cache aside: %w", err)
+		}
+	}
+	if err := os.Rename(clonePath, cachePath); err != nil {
+		return "", fmt.Errorf("failed to move clone into the cache: %w", err)
+	}
 
-// pullTemplate pulls latest changes for a cached template
-func (m *Manager) pullTemplate(cachePath string, token string) error {
-	// We need to re-initialize git to pull, since we removed .git
-	// So it's easier to just re-clone
-	return fmt.Errorf("pull not supported, please re-clone")
+	return commit, nil
 }
 
-// buildAuthenticatedURL creates a GitHub URL with token authentication
-func (m *Manager) buildAuthenticatedURL(repoURL string, token string) string {
-	// Parse the URL to insert the token
-	// Format: https://TOKEN@github.com/user/repo.git
+// pullTemplate updates a cached clone by fetching new commits and checking out the template ref
+func (m *Manager) pullTemplate(template *Template, cachePath string, token string) (string, error) {
+	if info, err := os.Stat(filepath.Join(cachePath, ".git")); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("cache entry has no git directory")
+	}
+
+	git := m.gitBackend()
+	previous, _ := git.revParse(cachePath, "HEAD")
 
-	if token == "" {
-		return repoURL
+	// A commit that is already present needs no fetch, a branch or tag of the same name does
+	if _, named := namedRef(git, cachePath, template.Branch); named || !isCommitSHA(template.Branch) ||
+		!hasCommit(git, cachePath, template.Branch) {
+		if err := git.fetch(cachePath, template.Repository, token); err != nil {
+			return "", fmt.Errorf("failed to fetch %s: %w", template.Repository, err)
+		}
 	}
 
-	// Remove https:// prefix if present
-	url := strings.TrimPrefix(repoURL, "https://")
-	url = strings.TrimPrefix(url, "http://")
+	// The working tree is rewritten in place, an interrupted checkout must not be trusted
+	if err := cache.MarkIncomplete(cachePath); err != nil {
+		return "", err
+	}
+	commit, err := checkoutRef(git, cachePath, template.Branch)
+	if err != nil {
+		return "", err
+	}
+	if err := cache.MarkComplete(cachePath); err != nil {
+		return "", err
+	}
 
-	// Build authenticated URL
-	return fmt.Sprintf("https://%s@%s", token, url)
+	if previous != "" && previous != commit {
+		fmt.Fprintf(m.out(), "Updated %s from %s to %s\n", template.Repository, ShortCommit(previous), ShortCommit(commit))
+	}
+
+	return commit, nil
 }
 
-// EnsureTemplateCached ensures a template is cached, downloading if necessary
+// EnsureTemplateCached ensures a template at its default branch and the templates it extends
+// are cached, downloading if necessary
 func (m *Manager) EnsureTemplateCached(archType config.ArchitectureType, token string) error {
-	// Check if already cached and valid
-	if m.IsCached(archType) {
-		return nil
-	}
-
-	// Download the template
-	return m.UpdateTemplate(archType, token)
+	return m.EnsureTemplateVersionCached(archType, "", token)
 }
 
 // GetTemplateFiles returns a list of files in a cached template
 func (m *Manager) GetTemplateFiles(archType config.ArchitectureType) ([]string, error) {
+	// Archived entries are listed from their archive index without extracting them
+	key, tmpl, err := m.templateEntry(archType)
+	if err != nil {
+		return nil, err
+	}
+	if m.cacheManager.Exists(key) {
+		index, err := m.cacheManager.ArchiveIndex(key)
+		if err != nil {
+			return nil, err
+		}
+		if index != nil {
+			return archivedFiles(index, tmpl.Subdir), nil
+		}
+	}
+
 	cachePath, err := m.GetTemplatePath(archType)
 	if err != nil {
 		return nil, err
@@ -228,27 +505,111 @@ func (m *Manager) GetTemplateFiles(archType config.ArchitectureType) ([]string,
 	return files, err
 }
 
+// archivedFiles returns the files of an archive index below subdir, relative to subdir and sorted
+func archivedFiles(index cache.FileManifest, subdir string) []string {
+	prefix := ""
+	if subdir != "" {
+		prefix = path.Clean(subdir) + "/"
+	}
+
+	var files []string
+	for file := range index {
+		if relPath, ok := strings.CutPrefix(file, prefix); ok {
+			files = append(files, filepath.FromSlash(relPath))
+		}
+	}
+	sort.Strings(files)
+
+	return files
+}
+
 // CopyTemplateToDestination copies a template to a destination directory
-func (m *Manager) CopyTemplateToDestination(archType config.ArchitectureType, destPath string) error {
+func (m *Manager) CopyTemplateToDestination(archType config.ArchitectureType, destPath string, data RenderData) error {
+	defer m.Hold()()
+
+	cachePath, err := m.GetTemplatePath(archType)
+	if err != nil {
+		return fmt.Errorf("failed to get template path: %w", err)
+	}
+
+	return m.CopyDirectoryToDestination(cachePath, destPath, data)
+}
+
+// CopyDirectoryToDestination copies a template directory (cached or local) to a destination directory.
+// Templated path elements are expanded and .tmpl files are rendered against data.
+// Templates it extends are copied first, so that its own files replace theirs.
+func (m *Manager) CopyDirectoryToDestination(srcPath, destPath string, data RenderData) error {
+	chain, err := m.GetTemplateChain(srcPath)
+	if err != nil {
+		return err
+	}
+
+	for i, dir := range chain {
+		// Descendant templates can exclude files of the templates they extend
+		var exclude []string
+		for _, child := range chain[i+1:] {
+			manifest, err := LoadManifest(child)
+			if err != nil {
+				return err
+			}
+			if manifest != nil {
+				exclude = append(exclude, manifest.Exclude...)
+			}
+		}
+
+		if err := copyDirectory(dir, destPath, data, copyOptions{exclude: exclude}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyOptions controls how copyDirectory treats the destination
+type copyOptions struct {
+	// merge combines files that already exist in the destination with the template
+	// files according to the merge rules of the template manifest
+	merge bool
+	// exclude lists additional patterns of files not to copy
+	exclude []string
+}
+
+// copyDirectory copies a template directory to a destination directory
+func copyDirectory(srcPath, destPath string, data RenderData, opts copyOptions) error {
 	// CRITICAL: Ensure destPath is absolute to avoid path resolution issues
 	if !filepath.IsAbs(destPath) {
 		return fmt.Errorf("BUG: destPath is not absolute: %s", destPath)
 	}
 
-	cachePath, err := m.GetTemplatePath(archType)
+	info, err := os.Stat(srcPath)
 	if err != nil {
-		return fmt.Errorf("failed to get template path: %w", err)
+		return fmt.Errorf("failed to access template directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("template path is not a directory: %s", srcPath)
+	}
+
+	// Refuse to copy a template into itself, the walk would never terminate
+	if rel, err := filepath.Rel(srcPath, destPath); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("destination %s is inside the template directory %s", destPath, srcPath)
 	}
 
+	manifest, err := LoadManifest(srcPath)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		manifest = &Manifest{}
+	}
 
-	// Copy all files from cache to destination
-	return filepath.Walk(cachePath, func(path string, info os.FileInfo, err error) error {
+	// Copy all files from the template directory to destination
+	return filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip the cache root directory
-		if path == cachePath {
+		// Skip the template root directory
+		if path == srcPath {
 			return nil
 		}
 
@@ -258,38 +619,92 @@ func (m *Manager) CopyTemplateToDestination(archType config.ArchitectureType, de
 		}
 
 		// Calculate destination path
-		relPath, err := filepath.Rel(cachePath, path)
+		relPath, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return err
+		}
+
+		// Skip the manifest and paths excluded by it
+		if manifest.IsExcluded(relPath) || matchesAny(opts.exclude, relPath) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Skip paths whose conditions do not hold for the template variables
+		included, err := manifest.IsIncluded(relPath, data)
+		if err != nil {
+			return err
+		}
+		if !included {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Expand templated path elements such as cmd/{{.ProjectName}}
+		renderedPath, err := RenderPath(relPath, data)
 		if err != nil {
 			return err
 		}
+		if renderedPath == "" {
+			// An element rendered empty, drop the path
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// BUG FIX: Use different variable name to avoid shadowing the destPath parameter
 		// This was causing incorrect path resolution
-		targetPath := filepath.Join(destPath, relPath)
+		targetPath := filepath.Join(destPath, renderedPath)
 
 		if info.IsDir() {
 			// Create directory
 			return os.MkdirAll(targetPath, info.Mode())
 		}
 
-		// Copy file
-		return copyFile(path, targetPath, info.Mode())
+		// Render .tmpl files and write them without the suffix
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read source file %s: %w", path, err)
+		}
+		if strings.HasSuffix(renderedPath, TemplateFileSuffix) {
+			renderedPath = strings.TrimSuffix(renderedPath, TemplateFileSuffix)
+			targetPath = strings.TrimSuffix(targetPath, TemplateFileSuffix)
+			if content, err = renderFile(filepath.Base(path), content, data); err != nil {
+				return err
+			}
+		}
+
+		// Combine the file with the one already generated by an earlier template
+		if opts.merge {
+			existing, err := os.ReadFile(targetPath)
+			if err == nil {
+				content, err = manifest.MergeFile(renderedPath, existing, content)
+				if err != nil {
+					return err
+				}
+			} else if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to read destination file %s: %w", targetPath, err)
+			}
+		}
+
+		return writeFile(targetPath, content, info.Mode())
 	})
 }
 
-// copyFile copies a file from src to dst
-func copyFile(src, dst string, mode os.FileMode) error {
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return fmt.Errorf("failed to read source file %s: %w", src, err)
-	}
-
+// writeFile writes content to dst, creating its directory
+func writeFile(dst string, content []byte, mode os.FileMode) error {
 	// Ensure destination directory exists
 	dstDir := filepath.Dir(dst)
 	if err := os.MkdirAll(dstDir, 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory %s: %w", dstDir, err)
 	}
 
-	if err := os.WriteFile(dst, data, mode); err != nil {
+	if err := os.WriteFile(dst, content, mode); err != nil {
 		return fmt.Errorf("failed to write destination file %s: %w", dst, err)
 	}
 
@@ -301,7 +716,23 @@ func (m *Manager) ClearAllCache() error {
 	return m.cacheManager.ClearCache()
 }
 
-// ClearTemplateCache clears cache for a specific template
-func (m *Manager) ClearTemplateCache(archType config.ArchitectureType) error {
-	return m.cacheManager.ClearTemplateCache(archType)
+// ClearTemplateCache clears the cache entries of a specific template, all versions included,
+// and returns how many entries were removed
+func (m *Manager) ClearTemplateCache(archType config.ArchitectureType) (int, error) {
+	entries, err := m.cacheManager.GetEntries()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for key, info := range entries {
+		if info.Template == string(archType) {
+			if err := m.cacheManager.RemoveEntry(key); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+
+	return removed, nil
 }
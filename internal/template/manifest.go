@@ -0,0 +1,269 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFileName is the name of the manifest file at the template root
+const ManifestFileName = "pick-your-go.yaml"
+
+// Variable types supported in template manifests
+const (
+	VariableTypeString      = "string"
+	VariableTypeBool        = "bool"
+	VariableTypeSelect      = "select"
+	VariableTypeMultiSelect = "multiselect"
+)
+
+// Manifest describes a template: its metadata, variables and generation rules
+type Manifest struct {
+	// Name is the display name of the template
+	Name string `yaml:"name"`
+	// Description is a short description of the template
+	Description string `yaml:"description"`
+	// Extends names the parent template (<template source>@<ref>) whose files this template builds on
+	Extends string `yaml:"extends"`
+	// Structure lists the main directories created by the template
+	Structure []string `yaml:"structure"`
+	// Variables are the custom values the template accepts
+	Variables []Variable `yaml:"variables"`
+	// Exclude lists paths (glob patterns) that are never copied to the project
+	Exclude []string `yaml:"exclude"`
+	// Conditions include paths only when their expression holds for the template variables
+	Conditions []Condition `yaml:"conditions"`
+	// Hooks are the post-generation steps run inside the generated project
+	Hooks []config.Hook `yaml:"hooks"`
+	// Merge decides how overlay files are combined with files that already exist in the project
+	Merge []MergeRule `yaml:"merge"`
+	// NextSteps are shown to the user after the project is generated
+	NextSteps []string `yaml:"next_steps"`
+	// Notes describe the layout of the generated project
+	Notes []string `yaml:"notes"`
+}
+
+// Variable is a custom template variable declared in the manifest
+type Variable struct {
+	// Name is the key used in templates (e.g. {{.database}})
+	Name string `yaml:"name"`
+	// Type is one of string, bool, select or multiselect
+	Type string `yaml:"type"`
+	// Prompt is the question shown in the interactive form
+	Prompt string `yaml:"prompt"`
+	// Description is the help text shown in the interactive form
+	Description string `yaml:"description"`
+	// Default is the value used when none is provided
+	Default interface{} `yaml:"default"`
+	// Options are the allowed values for select and multiselect variables
+	Options []string `yaml:"options"`
+	// Validate is a regular expression string values must match
+	Validate string `yaml:"validate"`
+}
+
+// LoadManifest reads the manifest from a template directory.
+// It returns nil without error when the template has no manifest.
+func LoadManifest(templateDir string) (*Manifest, error) {
+	path := filepath.Join(templateDir, ManifestFileName)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return ParseManifest(data)
+}
+
+// ParseManifest parses and validates manifest content
+func ParseManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := yaml.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Validate checks the manifest declarations and fills in defaults
+func (mf *Manifest) Validate() error {
+	seen := make(map[string]bool)
+	for i := range mf.Variables {
+		v := &mf.Variables[i]
+		if v.Name == "" {
+			return fmt.Errorf("variable #%d: name is required", i+1)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable %q is declared more than once", v.Name)
+		}
+		seen[v.Name] = true
+
+		switch v.Type {
+		case "":
+			v.Type = VariableTypeString
+		case VariableTypeString, VariableTypeBool:
+		case VariableTypeSelect, VariableTypeMultiSelect:
+			if len(v.Options) == 0 {
+				return fmt.Errorf("variable %q: %s requires options", v.Name, v.Type)
+			}
+			if v.Type == VariableTypeSelect && v.Default == nil {
+				v.Default = v.Options[0]
+			}
+		default:
+			return fmt.Errorf("variable %q: unknown type %q", v.Name, v.Type)
+		}
+
+		if v.Validate != "" {
+			if _, err := regexp.Compile(v.Validate); err != nil {
+				return fmt.Errorf("variable %q: invalid validation pattern: %w", v.Name, err)
+			}
+		}
+
+		if v.Prompt == "" {
+			v.Prompt = v.Name
+		}
+	}
+
+	for i := range mf.Conditions {
+		if err := mf.Conditions[i].compile(); err != nil {
+			return fmt.Errorf("condition #%d: %w", i+1, err)
+		}
+	}
+
+	if mf.Extends != "" {
+		if _, err := ParseExtends(mf.Extends); err != nil {
+			return err
+		}
+	}
+
+	for i, rule := range mf.Merge {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("merge rule #%d: %w", i+1, err)
+		}
+	}
+
+	return config.ValidateHooks(mf.Hooks)
+}
+
+// getDefaultManifest returns the manifest used for templates that do not ship one.
+// Name and description are filled in from the template definition.
+func getDefaultManifest(archType config.ArchitectureType) *Manifest {
+	nextSteps := []string{
+		"Review the generated structure",
+		"Start building your application!",
+	}
+
+	switch archType {
+	case config.LayeredArchitecture:
+		return &Manifest{
+			Structure: []string{
+				"cmd/",
+				"internal/domain/",
+				"internal/presentation/http/",
+				"internal/infrastructure/database/",
+				"internal/infrastructure/cache/",
+				"pkg/",
+				"configs/",
+				"docs/",
+			},
+			NextSteps: nextSteps,
+			Notes: []string{
+				"Presentation layer is in /internal/presentation",
+				"Business logic is in /internal/domain",
+				"Data access is in /internal/infrastructure",
+			},
+		}
+	case config.ModularArchitecture:
+		return &Manifest{
+			Structure: []string{
+				"cmd/",
+				"internal/modules/",
+				"internal/shared/",
+				"internal/shared/domain/",
+				"internal/shared/infrastructure/",
+				"pkg/",
+				"configs/",
+				"docs/",
+			},
+			NextSteps: nextSteps,
+			Notes: []string{
+				"Each module is self-contained in /internal/modules",
+				"Shared code is in /internal/shared",
+				"Follow DDD principles for module boundaries",
+			},
+		}
+	case config.HexagonalArchitecture:
+		return &Manifest{
+			Structure: []string{
+				"cmd/",
+				"internal/domain/",
+				"internal/ports/",
+				"internal/ports/in/",
+				"internal/ports/out/",
+				"internal/adapters/",
+				"internal/adapters/in/",
+				"internal/adapters/out/",
+				"internal/app/",
+				"pkg/",
+				"configs/",
+				"docs/",
+			},
+			NextSteps: nextSteps,
+			Notes: []string{
+				"Domain logic is in /internal/domain",
+				"Ports are in /internal/ports",
+				"Adapters are in /internal/adapters",
+			},
+		}
+	default:
+		return &Manifest{
+			NextSteps: nextSteps,
+		}
+	}
+}
+
+// IsExcluded reports whether a template-relative path is excluded from generation
+func (mf *Manifest) IsExcluded(relPath string) bool {
+	if relPath == ManifestFileName {
+		return true
+	}
+
+	for _, pattern := range mf.Exclude {
+		if MatchPath(pattern, relPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsIncluded reports whether every condition attached to a template-relative path holds
+func (mf *Manifest) IsIncluded(relPath string, data RenderData) (bool, error) {
+	for i := range mf.Conditions {
+		condition := &mf.Conditions[i]
+		if !MatchPath(condition.Path, relPath) {
+			continue
+		}
+
+		ok, err := condition.Evaluate(data)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
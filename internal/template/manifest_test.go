@@ -0,0 +1,173 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseManifest tests parsing a manifest with all sections
+func TestParseManifest(t *testing.T) {
+	manifest, err := ParseManifest([]byte(`
+name: Company Layered
+description: Layered template with company conventions
+structure:
+  - cmd/
+  - internal/
+variables:
+  - name: database
+    type: select
+    options: [postgres, mysql]
+    default: postgres
+  - name: service_name
+exclude:
+  - docs/internal/**
+  - "*.orig"
+hooks:
+  - run: go mod tidy
+next_steps:
+  - Run make dev
+notes:
+  - HTTP handlers live in /internal/presentation
+`))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+
+	if manifest.Name != "Company Layered" {
+		t.Errorf("expected name 'Company Layered', got '%s'", manifest.Name)
+	}
+	if len(manifest.Structure) != 2 {
+		t.Errorf("expected 2 structure entries, got %d", len(manifest.Structure))
+	}
+	if len(manifest.Variables) != 2 {
+		t.Fatalf("expected 2 variables, got %d", len(manifest.Variables))
+	}
+
+	// Defaults are filled in
+	serviceName := manifest.Variables[1]
+	if serviceName.Type != VariableTypeString {
+		t.Errorf("expected default type '%s', got '%s'", VariableTypeString, serviceName.Type)
+	}
+	if serviceName.Prompt != "service_name" {
+		t.Errorf("expected prompt to default to name, got '%s'", serviceName.Prompt)
+	}
+	if manifest.Hooks[0].Name != "go mod tidy" {
+		t.Errorf("expected hook name to default to command, got '%s'", manifest.Hooks[0].Name)
+	}
+}
+
+// TestParseManifestInvalid tests manifest validation errors
+func TestParseManifestInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "Variable without name",
+			content: "variables:\n  - type: string\n",
+		},
+		{
+			name:    "Unknown variable type",
+			content: "variables:\n  - name: a\n    type: number\n",
+		},
+		{
+			name:    "Select without options",
+			content: "variables:\n  - name: a\n    type: select\n",
+		},
+		{
+			name:    "Invalid validation pattern",
+			content: "variables:\n  - name: a\n    validate: \"[\"\n",
+		},
+		{
+			name:    "Hook without command",
+			content: "hooks:\n  - name: tidy\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseManifest([]byte(tt.content)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestLoadManifestMissing tests that templates without a manifest yield nil
+func TestLoadManifestMissing(t *testing.T) {
+	manifest, err := LoadManifest(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if manifest != nil {
+		t.Errorf("expected nil manifest, got %+v", manifest)
+	}
+}
+
+// TestMatchPath tests glob matching of template paths
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide.md", true},
+		{"vendor", "vendor/github.com/x/y.go", true},
+		{"docs/*.md", "docs/guide.md", true},
+		{"docs/*.md", "other/docs/guide.md", false},
+		{"internal/infrastructure/cache/**", "internal/infrastructure/cache/redis.go", true},
+		{"internal/infrastructure/cache/**", "internal/infrastructure/cache", true},
+		{"internal/infrastructure/cache/**", "internal/infrastructure/database/db.go", false},
+		{"internal/**/testdata", "internal/a/b/testdata/file.txt", true},
+		{"internal/**/testdata", "internal/testdata", true},
+		{"internal/cache", "internal/cache/redis.go", true},
+		{"internal/cache", "internal/cachefoo/redis.go", false},
+		{"**/*.tmp", "a/b/c.tmp", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestCopyDirectoryExcludesPaths tests that the manifest and excluded paths are not copied
+func TestCopyDirectoryExcludesPaths(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		ManifestFileName:       "exclude:\n  - docs/internal\n",
+		"go.mod":               "module example.com/tpl\n",
+		"docs/README.md":       "docs",
+		"docs/internal/ADR.md": "internal docs",
+	}
+	for name, content := range files {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	destDir := filepath.Join(t.TempDir(), "project")
+	m := &Manager{}
+	if err := m.CopyDirectoryToDestination(srcDir, destDir, RenderData{}); err != nil {
+		t.Fatalf("CopyDirectoryToDestination failed: %v", err)
+	}
+
+	for name, wantExists := range map[string]bool{
+		"go.mod":               true,
+		"docs/README.md":       true,
+		ManifestFileName:       false,
+		"docs/internal/ADR.md": false,
+	} {
+		_, err := os.Stat(filepath.Join(destDir, name))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("%s: exists = %v, want %v", name, exists, wantExists)
+		}
+	}
+}
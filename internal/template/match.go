@@ -0,0 +1,76 @@
+package template
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MatchPath reports whether a template-relative path matches a glob pattern.
+//
+// Patterns follow a small subset of .gitignore semantics:
+//   - a pattern without a slash matches any path element (e.g. "*.md", "vendor")
+//   - a pattern with a slash is anchored at the template root (e.g. "docs/*.md")
+//   - "**" matches zero or more directories (e.g. "internal/**/testdata")
+//   - a pattern matching a directory also matches everything below it
+func MatchPath(pattern, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	if pattern == "" || relPath == "" {
+		return false
+	}
+
+	segments := strings.Split(relPath, "/")
+
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		for _, segment := range segments {
+			if ok, _ := path.Match(pattern, segment); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), segments)
+}
+
+// matchSegments matches pattern segments against path segments.
+// A path matches when the pattern matches it or one of its parent directories.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		// Pattern consumed: the path itself or a directory above it matched
+		return true
+	}
+
+	if pattern[0] == "**" {
+		// "**" matches zero or more path segments
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// matchesAny reports whether any of the patterns matches relPath
+func matchesAny(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if MatchPath(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
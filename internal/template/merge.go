@@ -0,0 +1,407 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// Merge strategies for overlay files that already exist in the project
+const (
+	// MergeStrategyGoMod adds the overlay requirements and replacements to go.mod,
+	// keeping the higher version when both declare a module
+	MergeStrategyGoMod = "gomod"
+	// MergeStrategyMakefile adds the overlay targets and variables to the Makefile,
+	// replacing targets and variables with the same name
+	MergeStrategyMakefile = "makefile"
+	// MergeStrategyYAML deep merges YAML mappings; overlay scalars win and new list items are appended
+	MergeStrategyYAML = "yaml"
+	// MergeStrategyOverwrite replaces the existing file with the overlay file
+	MergeStrategyOverwrite = "overwrite"
+	// MergeStrategySkip keeps the existing file
+	MergeStrategySkip = "skip"
+)
+
+// MergeRule selects the merge strategy for paths matching a pattern
+type MergeRule struct {
+	// Path is a glob pattern (see MatchPath) selecting the files the rule applies to
+	Path string `yaml:"path"`
+	// Strategy is one of gomod, makefile, yaml, overwrite or skip
+	Strategy string `yaml:"strategy"`
+}
+
+// validate checks the rule declaration
+func (r MergeRule) validate() error {
+	if r.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	switch r.Strategy {
+	case MergeStrategyGoMod, MergeStrategyMakefile, MergeStrategyYAML, MergeStrategyOverwrite, MergeStrategySkip:
+		return nil
+	case "":
+		return fmt.Errorf("merge rule for %q: strategy is required", r.Path)
+	default:
+		return fmt.Errorf("merge rule for %q: unknown strategy %q", r.Path, r.Strategy)
+	}
+}
+
+// MergeStrategy returns the strategy for a project-relative path.
+// The first matching merge rule wins; go.mod, Makefiles and YAML files have built-in strategies.
+// An empty result means the file cannot be merged.
+func (mf *Manifest) MergeStrategy(relPath string) string {
+	for _, rule := range mf.Merge {
+		if MatchPath(rule.Path, relPath) {
+			return rule.Strategy
+		}
+	}
+
+	name := filepath.Base(relPath)
+	switch {
+	case name == "go.mod":
+		return MergeStrategyGoMod
+	case name == "Makefile" || name == "GNUmakefile" || strings.HasSuffix(name, ".mk"):
+		return MergeStrategyMakefile
+	case strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml"):
+		return MergeStrategyYAML
+	default:
+		return ""
+	}
+}
+
+// MergeFile combines an overlay file with the file already generated at relPath
+func (mf *Manifest) MergeFile(relPath string, existing, incoming []byte) ([]byte, error) {
+	if bytes.Equal(existing, incoming) {
+		return existing, nil
+	}
+
+	var merged []byte
+	var err error
+	switch strategy := mf.MergeStrategy(relPath); strategy {
+	case MergeStrategyGoMod:
+		merged, err = mergeGoMod(existing, incoming)
+	case MergeStrategyMakefile:
+		merged = mergeMakefile(existing, incoming)
+	case MergeStrategyYAML:
+		merged, err = mergeYAML(existing, incoming)
+	case MergeStrategyOverwrite:
+		merged = incoming
+	case MergeStrategySkip:
+		merged = existing
+	default:
+		return nil, fmt.Errorf("%s already exists in the project; add a merge rule for it to the overlay manifest", relPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge %s: %w", relPath, err)
+	}
+
+	return merged, nil
+}
+
+// mergeGoMod adds the requirements and replacements of the overlay go.mod to the existing one.
+// The module path of the existing go.mod is kept and the newer go version wins.
+func mergeGoMod(existing, incoming []byte) ([]byte, error) {
+	base, err := modfile.Parse("go.mod", existing, nil)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := modfile.Parse("go.mod", incoming, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if overlay.Go != nil && (base.Go == nil || semver.Compare("v"+overlay.Go.Version, "v"+base.Go.Version) > 0) {
+		if err := base.AddGoStmt(overlay.Go.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, req := range overlay.Require {
+		var current *modfile.Require
+		for _, r := range base.Require {
+			if r.Mod.Path == req.Mod.Path {
+				current = r
+				break
+			}
+		}
+
+		if current == nil {
+			base.AddNewRequire(req.Mod.Path, req.Mod.Version, req.Indirect)
+			continue
+		}
+
+		version := current.Mod.Version
+		if semver.Compare(req.Mod.Version, version) > 0 {
+			version = req.Mod.Version
+		}
+		indirect := current.Indirect && req.Indirect
+		if version != current.Mod.Version || indirect != current.Indirect {
+			if err := base.DropRequire(req.Mod.Path); err != nil {
+				return nil, err
+			}
+			base.AddNewRequire(req.Mod.Path, version, indirect)
+		}
+	}
+
+	for _, rep := range overlay.Replace {
+		var current *modfile.Replace
+		for _, r := range base.Replace {
+			if r.Old.Path == rep.Old.Path && r.Old.Version == rep.Old.Version {
+				current = r
+				break
+			}
+		}
+
+		if current == nil {
+			if err := base.AddReplace(rep.Old.Path, rep.Old.Version, rep.New.Path, rep.New.Version); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if current.New != rep.New {
+			return nil, fmt.Errorf("conflicting replacements for %s: %s and %s", rep.Old.Path, current.New, rep.New)
+		}
+	}
+
+	base.Cleanup()
+	return base.Format()
+}
+
+// Makefile line patterns
+var (
+	makefileVariablePattern = regexp.MustCompile(`^(?:export\s+|override\s+)?([A-Za-z0-9_.-]+)\s*(?:::=|:=|\?=|\+=|!=|=)`)
+	makefileRulePattern     = regexp.MustCompile(`^([^\s:=#][^:=#]*?)\s*::?(?:[^=]|$)`)
+)
+
+// makefileEntry is a rule, a variable assignment or another line of a Makefile,
+// together with the comments and blank lines preceding it
+type makefileEntry struct {
+	// key is "rule:<targets>" or "var:<name>", empty for other lines
+	key   string
+	lines []string
+	// phony lists the targets of a .PHONY declaration
+	phony []string
+}
+
+// parseMakefile splits a Makefile into entries
+func parseMakefile(content string) []*makefileEntry {
+	var entries []*makefileEntry
+	var pending []string
+	var current *makefileEntry
+
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	for i, line := range lines {
+		continued := i > 0 && strings.HasSuffix(lines[i-1], "\\")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case current != nil && (continued || (strings.HasPrefix(line, "\t") && strings.HasPrefix(current.key, "rule:"))):
+			// Recipe or continuation line
+			current.lines = append(current.lines, append(pending, line)...)
+			pending = nil
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			pending = append(pending, line)
+		default:
+			current = &makefileEntry{lines: append(pending, line)}
+			pending = nil
+
+			if match := makefileVariablePattern.FindStringSubmatch(line); match != nil {
+				current.key = "var:" + match[1]
+			} else if match := makefileRulePattern.FindStringSubmatch(line); match != nil {
+				targets := strings.Join(strings.Fields(match[1]), " ")
+				current.key = "rule:" + targets
+				if targets == ".PHONY" {
+					_, prerequisites, _ := strings.Cut(line, ":")
+					current.phony = strings.Fields(prerequisites)
+				}
+			}
+			entries = append(entries, current)
+		}
+	}
+
+	if len(pending) > 0 {
+		entries = append(entries, &makefileEntry{lines: pending})
+	}
+
+	return entries
+}
+
+// mergeMakefile adds the overlay targets and variables to the existing Makefile.
+// Targets and variables defined in both are replaced by the overlay definition,
+// .PHONY declarations are combined and other lines are added unless already present.
+func mergeMakefile(existing, incoming []byte) []byte {
+	entries := parseMakefile(string(existing))
+
+	index := make(map[string]int)
+	phony := make(map[string]bool)
+	present := make(map[string]bool)
+	for i, entry := range entries {
+		for _, target := range entry.phony {
+			phony[target] = true
+		}
+		if entry.key != "" && entry.phony == nil {
+			index[entry.key] = i
+		}
+		if entry.key == "" {
+			present[strings.TrimSpace(strings.Join(entry.lines, "\n"))] = true
+		}
+	}
+
+	appended := len(entries)
+	for _, entry := range parseMakefile(string(incoming)) {
+		switch {
+		case entry.phony != nil:
+			var targets []string
+			for _, target := range entry.phony {
+				if !phony[target] {
+					phony[target] = true
+					targets = append(targets, target)
+				}
+			}
+			if len(targets) == 0 {
+				continue
+			}
+			lines := append([]string{}, entry.lines[:len(entry.lines)-1]...)
+			entries = append(entries, &makefileEntry{key: entry.key, lines: append(lines, ".PHONY: "+strings.Join(targets, " ")), phony: targets})
+		case entry.key == "":
+			text := strings.TrimSpace(strings.Join(entry.lines, "\n"))
+			if text == "" || present[text] {
+				continue
+			}
+			present[text] = true
+			entries = append(entries, entry)
+		default:
+			if i, ok := index[entry.key]; ok {
+				entries[i] = entry
+				continue
+			}
+			index[entry.key] = len(entries)
+			entries = append(entries, entry)
+		}
+	}
+
+	var out []string
+	for i, entry := range entries {
+		// Separate rules and the first appended entry from the previous lines
+		separate := strings.HasPrefix(entry.key, "rule:") || i == appended
+		if separate && len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" && strings.TrimSpace(entry.lines[0]) != "" {
+			out = append(out, "")
+		}
+		out = append(out, entry.lines...)
+	}
+
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// mergeYAML deep merges the overlay YAML document into the existing one
+func mergeYAML(existing, incoming []byte) ([]byte, error) {
+	base, err := parseYAMLDocument(existing)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := parseYAMLDocument(incoming)
+	if err != nil {
+		return nil, err
+	}
+
+	if base == nil {
+		return incoming, nil
+	}
+	if overlay == nil {
+		return existing, nil
+	}
+
+	base.Content[0] = mergeYAMLNodes(base.Content[0], overlay.Content[0])
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(base); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parseYAMLDocument parses a single YAML document, returning nil for empty content
+func parseYAMLDocument(content []byte) (*yaml.Node, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+
+	var doc yaml.Node
+	if err := decoder.Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var next yaml.Node
+	if err := decoder.Decode(&next); err != io.EOF {
+		return nil, fmt.Errorf("multi-document YAML cannot be merged, use the overwrite or skip strategy")
+	}
+
+	return &doc, nil
+}
+
+// mergeYAMLNodes merges src into dst: mappings are merged key by key,
+// sequences get the src items they do not contain yet and other values are replaced by src
+func mergeYAMLNodes(dst, src *yaml.Node) *yaml.Node {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+
+			found := false
+			for j := 0; j+1 < len(dst.Content); j += 2 {
+				if dst.Content[j].Value == key.Value {
+					dst.Content[j+1] = mergeYAMLNodes(dst.Content[j+1], value)
+					found = true
+					break
+				}
+			}
+			if !found {
+				dst.Content = append(dst.Content, key, value)
+			}
+		}
+		return dst
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		for _, item := range src.Content {
+			found := false
+			for _, existing := range dst.Content {
+				if yamlNodesEqual(existing, item) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				dst.Content = append(dst.Content, item)
+			}
+		}
+		return dst
+	default:
+		return src
+	}
+}
+
+// yamlNodesEqual reports whether two YAML nodes hold the same content
+func yamlNodesEqual(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !yamlNodesEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMergeStrategy tests explicit merge rules and the built-in strategies
+func TestMergeStrategy(t *testing.T) {
+	manifest := &Manifest{
+		Merge: []MergeRule{
+			{Path: "deploy/*.yaml", Strategy: MergeStrategyOverwrite},
+			{Path: "README.md", Strategy: MergeStrategySkip},
+		},
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"go.mod", MergeStrategyGoMod},
+		{"Makefile", MergeStrategyMakefile},
+		{"build/docker.mk", MergeStrategyMakefile},
+		{".github/workflows/ci.yml", MergeStrategyYAML},
+		{"configs/config.yaml", MergeStrategyYAML},
+		{"deploy/app.yaml", MergeStrategyOverwrite},
+		{"README.md", MergeStrategySkip},
+		{"main.go", ""},
+	}
+
+	for _, tt := range tests {
+		if got := manifest.MergeStrategy(tt.path); got != tt.expected {
+			t.Errorf("MergeStrategy(%q) = %q, expected %q", tt.path, got, tt.expected)
+		}
+	}
+}
+
+// TestMergeFileConflict tests that files without a merge strategy conflict unless identical
+func TestMergeFileConflict(t *testing.T) {
+	manifest := &Manifest{}
+
+	if _, err := manifest.MergeFile("main.go", []byte("package main\n"), []byte("package other\n")); err == nil {
+		t.Errorf("expected a conflict error for main.go")
+	}
+
+	merged, err := manifest.MergeFile("main.go", []byte("package main\n"), []byte("package main\n"))
+	if err != nil {
+		t.Fatalf("identical files should not conflict: %v", err)
+	}
+	if string(merged) != "package main\n" {
+		t.Errorf("unexpected content %q", merged)
+	}
+}
+
+// TestMergeGoMod tests merging go.mod requirements
+func TestMergeGoMod(t *testing.T) {
+	existing := `module github.com/example/base
+
+go 1.21
+
+require (
+	github.com/spf13/cobra v1.8.0
+	golang.org/x/sys v0.10.0 // indirect
+)
+`
+	incoming := `module github.com/example/overlay-otel
+
+go 1.22
+
+require (
+	github.com/spf13/cobra v1.7.0
+	go.opentelemetry.io/otel v1.24.0
+	golang.org/x/sys v0.15.0
+)
+
+replace github.com/example/lib => ../lib
+`
+
+	merged, err := mergeGoMod([]byte(existing), []byte(incoming))
+	if err != nil {
+		t.Fatalf("mergeGoMod failed: %v", err)
+	}
+	content := string(merged)
+
+	for _, expected := range []string{
+		"module github.com/example/base",
+		"go 1.22",
+		"github.com/spf13/cobra v1.8.0",
+		"go.opentelemetry.io/otel v1.24.0",
+		"golang.org/x/sys v0.15.0",
+		"replace github.com/example/lib => ../lib",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected merged go.mod to contain %q:\n%s", expected, content)
+		}
+	}
+
+	if strings.Contains(content, "overlay-otel") {
+		t.Errorf("overlay module path should not be kept:\n%s", content)
+	}
+	if strings.Contains(content, "golang.org/x/sys v0.15.0 // indirect") {
+		t.Errorf("a direct requirement of the overlay should not stay indirect:\n%s", content)
+	}
+}
+
+// TestMergeMakefile tests merging Makefile targets, variables and .PHONY declarations
+func TestMergeMakefile(t *testing.T) {
+	existing := `APP := app
+
+.PHONY: build test
+
+# Build the binary
+build:
+	go build -o bin/$(APP) ./cmd
+
+test:
+	go test ./...
+`
+	incoming := `IMAGE ?= app:latest
+
+.PHONY: test docker
+
+test:
+	go test -race ./...
+
+# Build the container image
+docker:
+	docker build -t $(IMAGE) .
+`
+
+	merged := string(mergeMakefile([]byte(existing), []byte(incoming)))
+
+	expected := `APP := app
+
+.PHONY: build test
+
+# Build the binary
+build:
+	go build -o bin/$(APP) ./cmd
+
+test:
+	go test -race ./...
+
+IMAGE ?= app:latest
+
+.PHONY: docker
+
+# Build the container image
+docker:
+	docker build -t $(IMAGE) .
+`
+	if merged != expected {
+		t.Errorf("unexpected merged Makefile:\n%s\nexpected:\n%s", merged, expected)
+	}
+}
+
+// TestMergeYAML tests deep merging of YAML documents
+func TestMergeYAML(t *testing.T) {
+	existing := `name: ci
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: go test ./...
+`
+	incoming := `on:
+  pull_request: {}
+jobs:
+  test:
+    runs-on: ubuntu-22.04
+    steps:
+      - uses: actions/checkout@v4
+      - run: go vet ./...
+  docker:
+    runs-on: ubuntu-latest
+`
+
+	merged, err := mergeYAML([]byte(existing), []byte(incoming))
+	if err != nil {
+		t.Fatalf("mergeYAML failed: %v", err)
+	}
+
+	expected := `name: ci
+on:
+  push:
+    branches: [main]
+  pull_request: {}
+jobs:
+  test:
+    runs-on: ubuntu-22.04
+    steps:
+      - uses: actions/checkout@v4
+      - run: go test ./...
+      - run: go vet ./...
+  docker:
+    runs-on: ubuntu-latest
+`
+	if string(merged) != expected {
+		t.Errorf("unexpected merged YAML:\n%s\nexpected:\n%s", merged, expected)
+	}
+}
+
+// TestMergeYAMLMultiDocument tests that multi-document YAML is rejected
+func TestMergeYAMLMultiDocument(t *testing.T) {
+	if _, err := mergeYAML([]byte("a: 1\n---\nb: 2\n"), []byte("c: 3\n")); err == nil {
+		t.Errorf("expected an error for multi-document YAML")
+	}
+}
@@ -0,0 +1,270 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// overlayTypePrefix tells overlays apart from templates of the same name in the cache metadata
+const overlayTypePrefix = "overlay-"
+
+// Overlay is a feature template (e.g. docker, ci) applied on top of the base template
+type Overlay struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Repository  string `json:"repository" yaml:"repository"`
+	Branch      string `json:"branch" yaml:"branch"`
+	// Subdir is the overlay directory inside the repository, empty for the repository root
+	Subdir string `json:"subdir,omitempty" yaml:"subdir,omitempty"`
+	// Refresh is the refresh policy of the cached overlay: never, always or a duration
+	Refresh string `json:"refresh,omitempty" yaml:"refresh,omitempty"`
+
+	// Dir is the directory of an overlay given as a local path instead of a registry name
+	Dir string `json:"-" yaml:"-"`
+}
+
+// template returns the overlay as a template definition for cloning
+func (o *Overlay) template() *Template {
+	return &Template{
+		Type:        config.ArchitectureType(overlayTypePrefix + o.Name),
+		Name:        o.Name,
+		Description: o.Description,
+		Repository:  o.Repository,
+		Branch:      o.Branch,
+		Subdir:      o.Subdir,
+		Refresh:     o.Refresh,
+	}
+}
+
+// overlayEntry returns the cache entry of an overlay at its branch or locked commit
+func (m *Manager) overlayEntry(overlay *Overlay) (string, *Template, error) {
+	tmpl, _, err := m.lockedTemplate(overlay.template())
+	if err != nil {
+		return "", nil, err
+	}
+	return tmpl.cacheKey(), tmpl, nil
+}
+
+// GetOverlays returns the overlays declared in the template registry
+func (m *Manager) GetOverlays() []*Overlay {
+	return m.overlays
+}
+
+// GetOverlay returns a registry overlay by name.
+// Names that look like paths (./overlays/otel) refer to a local overlay directory.
+func (m *Manager) GetOverlay(name string) (*Overlay, error) {
+	for _, overlay := range m.overlays {
+		if overlay.Name == name {
+			return overlay, nil
+		}
+	}
+
+	if strings.HasPrefix(name, ".") || strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		dir, err := filepath.Abs(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve overlay directory: %w", err)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to access overlay directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("overlay path is not a directory: %s", dir)
+		}
+		return &Overlay{Name: filepath.Base(dir), Dir: dir}, nil
+	}
+
+	return nil, fmt.Errorf("overlay not found: %s", name)
+}
+
+// GetOverlaysByName resolves a list of overlay names in order, rejecting duplicates
+func (m *Manager) GetOverlaysByName(names []string) ([]*Overlay, error) {
+	overlays := make([]*Overlay, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("overlay %q is selected more than once", name)
+		}
+		seen[name] = true
+
+		overlay, err := m.GetOverlay(name)
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, overlay)
+	}
+	return overlays, nil
+}
+
+// IsOverlayCached checks if an overlay is available without downloading it
+func (m *Manager) IsOverlayCached(overlay *Overlay) bool {
+	if overlay.Dir != "" {
+		return true
+	}
+	key, _, err := m.overlayEntry(overlay)
+	return err == nil && m.cacheManager.Exists(key)
+}
+
+// IsOverlayUpdateAvailable checks whether a cached overlay points to a newer commit upstream
+func (m *Manager) IsOverlayUpdateAvailable(overlay *Overlay, token string) (bool, error) {
+	if overlay.Dir != "" {
+		return false, nil
+	}
+	key, tmpl, err := m.overlayEntry(overlay)
+	if err != nil {
+		return false, err
+	}
+	return m.updateAvailable(key, tmpl, token)
+}
+
+// UpdateOverlay downloads or updates an overlay from GitHub
+func (m *Manager) UpdateOverlay(overlay *Overlay, token string) error {
+	if overlay.Dir != "" {
+		return nil
+	}
+	return m.updateTemplate(overlay.template(), token)
+}
+
+// EnsureOverlayCached ensures an overlay is cached, downloading if necessary
+func (m *Manager) EnsureOverlayCached(overlay *Overlay, token string) error {
+	if overlay.Dir != "" {
+		return nil
+	}
+	_, err := m.ensureCached(overlay.template(), token)
+	return err
+}
+
+// GetOverlayPath returns the directory holding the overlay files.
+// Archived overlays are extracted until the enclosing Hold is released.
+func (m *Manager) GetOverlayPath(overlay *Overlay) (string, error) {
+	defer m.Hold()()
+
+	if overlay.Dir != "" {
+		return overlay.Dir, nil
+	}
+	key, tmpl, err := m.overlayEntry(overlay)
+	if err != nil {
+		return "", err
+	}
+	if !m.cacheManager.Exists(key) {
+		return "", fmt.Errorf("overlay not cached: %s", overlay.Name)
+	}
+	return m.entryDir(key, tmpl)
+}
+
+// GetOverlayManifest returns the manifest of an available overlay.
+// Overlays without a manifest get one named after the overlay.
+func (m *Manager) GetOverlayManifest(overlay *Overlay) (*Manifest, error) {
+	defer m.Hold()()
+
+	overlayPath, err := m.GetOverlayPath(overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := LoadManifest(overlayPath)
+	if err != nil {
+		return nil, fmt.Errorf("overlay %s: %w", overlay.Name, err)
+	}
+	if manifest == nil {
+		manifest = &Manifest{}
+	}
+	if manifest.Name == "" {
+		manifest.Name = overlay.Name
+	}
+	if manifest.Description == "" {
+		manifest.Description = overlay.Description
+	}
+
+	return manifest, nil
+}
+
+// ResolveOverlayManifests makes the named overlays available and returns their manifests in order
+func (m *Manager) ResolveOverlayManifests(names []string, token string) ([]*Manifest, error) {
+	overlays, err := m.GetOverlaysByName(names)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := make([]*Manifest, 0, len(overlays))
+	for _, overlay := range overlays {
+		if err := m.EnsureOverlayCached(overlay, token); err != nil {
+			return nil, fmt.Errorf("failed to ensure overlay %s is cached: %w", overlay.Name, err)
+		}
+		manifest, err := m.GetOverlayManifest(overlay)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// GetOverlaySource returns the source of an overlay for the trust store
+func (m *Manager) GetOverlaySource(overlay *Overlay, hooks []config.Hook) (TemplateSource, error) {
+	if overlay.Dir != "" {
+		return GetDirectorySource(overlay.Dir, hooks)
+	}
+
+	source := TemplateSource{Repository: overlay.Repository}
+	if key, _, err := m.overlayEntry(overlay); err == nil {
+		if info, err := m.cacheManager.GetCacheInfo(key); err == nil {
+			source.Commit = info.Commit
+		}
+	}
+	return source, nil
+}
+
+// ApplyOverlay copies an overlay into a generated project.
+// Files that already exist are combined according to the overlay merge rules.
+func (m *Manager) ApplyOverlay(overlay *Overlay, destPath string, data RenderData) error {
+	defer m.Hold()()
+
+	overlayPath, err := m.GetOverlayPath(overlay)
+	if err != nil {
+		return err
+	}
+
+	return copyDirectory(overlayPath, destPath, data, copyOptions{merge: true})
+}
+
+// WithOverlays returns a copy of the manifest extended with the overlay manifests.
+// Overlay variables, structure, hooks, next steps and notes are added after the
+// manifest's own; variables already declared are not redeclared.
+func (mf *Manifest) WithOverlays(overlays []*Manifest) *Manifest {
+	if len(overlays) == 0 {
+		return mf
+	}
+
+	combined := *mf
+	combined.Structure = append([]string{}, mf.Structure...)
+	combined.Variables = append([]Variable{}, mf.Variables...)
+	combined.Hooks = append([]config.Hook{}, mf.Hooks...)
+	combined.NextSteps = append([]string{}, mf.NextSteps...)
+	combined.Notes = append([]string{}, mf.Notes...)
+
+	declared := make(map[string]bool)
+	for _, v := range mf.Variables {
+		declared[v.Name] = true
+	}
+
+	for _, overlay := range overlays {
+		for _, v := range overlay.Variables {
+			if !declared[v.Name] {
+				declared[v.Name] = true
+				combined.Variables = append(combined.Variables, v)
+			}
+		}
+		combined.Structure = append(combined.Structure, overlay.Structure...)
+		combined.Hooks = append(combined.Hooks, overlay.Hooks...)
+		combined.NextSteps = append(combined.NextSteps, overlay.NextSteps...)
+		combined.Notes = append(combined.Notes, overlay.Notes...)
+	}
+
+	return &combined
+}
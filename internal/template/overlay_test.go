@@ -0,0 +1,181 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// TestApplyOverlay tests applying a local overlay on top of a copied template
+func TestApplyOverlay(t *testing.T) {
+	base := t.TempDir()
+	writeFiles(t, base, map[string]string{
+		"go.mod":   "module github.com/example/base\n\ngo 1.21\n",
+		"Makefile": "build:\n\tgo build ./...\n",
+		"main.go":  "package main\n",
+	})
+
+	overlayDir := filepath.Join(t.TempDir(), "docker")
+	writeFiles(t, overlayDir, map[string]string{
+		ManifestFileName: "name: Docker\nexclude:\n  - NOTES.md\n",
+		"go.mod":         "module github.com/example/docker\n\ngo 1.21\n\nrequire github.com/docker/go-units v0.5.0\n",
+		"Makefile":       "docker:\n\tdocker build -t {{.ProjectName}} .\n",
+		"Dockerfile.tmpl": "FROM golang\n" +
+			"WORKDIR /src/{{.ProjectName}}\n",
+		"NOTES.md": "not copied\n",
+	})
+
+	dest := filepath.Join(t.TempDir(), "project")
+	manager := &Manager{}
+	data := RenderData{"ProjectName": "demo"}
+
+	if err := manager.CopyDirectoryToDestination(base, dest, data); err != nil {
+		t.Fatalf("CopyDirectoryToDestination failed: %v", err)
+	}
+
+	overlay, err := manager.GetOverlay(overlayDir)
+	if err != nil {
+		t.Fatalf("GetOverlay failed: %v", err)
+	}
+	if overlay.Name != "docker" {
+		t.Errorf("expected overlay name docker, got %q", overlay.Name)
+	}
+
+	if err := manager.ApplyOverlay(overlay, dest, data); err != nil {
+		t.Fatalf("ApplyOverlay failed: %v", err)
+	}
+
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		return string(content)
+	}
+
+	if goMod := read("go.mod"); !strings.Contains(goMod, "module github.com/example/base") || !strings.Contains(goMod, "github.com/docker/go-units v0.5.0") {
+		t.Errorf("unexpected go.mod:\n%s", goMod)
+	}
+	if makefile := read("Makefile"); !strings.Contains(makefile, "build:") || !strings.Contains(makefile, "docker build -t {{.ProjectName}} .") {
+		t.Errorf("unexpected Makefile:\n%s", makefile)
+	}
+	if dockerfile := read("Dockerfile"); dockerfile != "FROM golang\nWORKDIR /src/demo\n" {
+		t.Errorf("unexpected Dockerfile:\n%s", dockerfile)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "NOTES.md")); !os.IsNotExist(err) {
+		t.Errorf("excluded overlay file should not be copied")
+	}
+}
+
+// TestApplyOverlayConflict tests that unmergeable files fail unless a merge rule resolves them
+func TestApplyOverlayConflict(t *testing.T) {
+	base := t.TempDir()
+	writeFiles(t, base, map[string]string{"main.go": "package main\n"})
+
+	overlayDir := filepath.Join(t.TempDir(), "server")
+	writeFiles(t, overlayDir, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+
+	dest := filepath.Join(t.TempDir(), "project")
+	manager := &Manager{}
+	if err := manager.CopyDirectoryToDestination(base, dest, RenderData{}); err != nil {
+		t.Fatalf("CopyDirectoryToDestination failed: %v", err)
+	}
+
+	overlay, err := manager.GetOverlay(overlayDir)
+	if err != nil {
+		t.Fatalf("GetOverlay failed: %v", err)
+	}
+
+	if err := manager.ApplyOverlay(overlay, dest, RenderData{}); err == nil {
+		t.Fatalf("expected a conflict error for main.go")
+	}
+
+	writeFiles(t, overlayDir, map[string]string{
+		ManifestFileName: "merge:\n  - path: main.go\n    strategy: overwrite\n",
+	})
+	if err := manager.ApplyOverlay(overlay, dest, RenderData{}); err != nil {
+		t.Fatalf("ApplyOverlay failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dest, "main.go"))
+	if err != nil {
+		t.Fatalf("failed to read main.go: %v", err)
+	}
+	if string(content) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("expected main.go to be overwritten, got %q", content)
+	}
+}
+
+// TestManifestWithOverlays tests combining a manifest with overlay manifests
+func TestManifestWithOverlays(t *testing.T) {
+	base := &Manifest{
+		Name:      "Layered",
+		Variables: []Variable{{Name: "database", Type: VariableTypeString}},
+		NextSteps: []string{"go mod tidy"},
+	}
+	docker := &Manifest{
+		Name: "Docker",
+		Variables: []Variable{
+			{Name: "database", Type: VariableTypeBool},
+			{Name: "registry", Type: VariableTypeString},
+		},
+		NextSteps: []string{"make docker"},
+	}
+
+	combined := base.WithOverlays([]*Manifest{docker})
+
+	if combined.Name != "Layered" {
+		t.Errorf("expected the base name, got %q", combined.Name)
+	}
+	if len(combined.Variables) != 2 || combined.Variables[0].Type != VariableTypeString || combined.Variables[1].Name != "registry" {
+		t.Errorf("unexpected variables: %+v", combined.Variables)
+	}
+	if strings.Join(combined.NextSteps, ",") != "go mod tidy,make docker" {
+		t.Errorf("unexpected next steps: %v", combined.NextSteps)
+	}
+	if len(base.NextSteps) != 1 {
+		t.Errorf("the base manifest should not be modified")
+	}
+}
+
+// TestRegistryOverlays tests validation of registry overlays
+func TestRegistryOverlays(t *testing.T) {
+	path := writeRegistry(t, `
+overlays:
+  - name: docker
+    description: Dockerfile and compose setup
+    repository: https://example.com/company/overlay-docker.git
+`)
+
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadRegistry failed: %v", err)
+	}
+	if len(registry.Overlays) != 1 || registry.Overlays[0].Branch != "main" {
+		t.Errorf("unexpected overlays: %+v", registry.Overlays)
+	}
+
+	path = writeRegistry(t, `
+overlays:
+  - name: docker
+`)
+	if _, err := LoadRegistry(path); err == nil {
+		t.Errorf("expected an error for an overlay without repository")
+	}
+}
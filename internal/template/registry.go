@@ -0,0 +1,200 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RegistryFileName is the name of the user template registry file
+	RegistryFileName = "templates.yaml"
+	// EnvRegistryFile overrides the location of the template registry file
+	EnvRegistryFile = "PICK_YOUR_GO_TEMPLATES_FILE"
+
+	// RegistryModeMerge adds registry templates to the defaults, overriding entries with the same type
+	RegistryModeMerge = "merge"
+	// RegistryModeReplace uses only the templates declared in the registry
+	RegistryModeReplace = "replace"
+)
+
+// Registry represents the user template registry file
+type Registry struct {
+	// Mode controls how registry templates are combined with the defaults (merge or replace)
+	Mode string `yaml:"mode"`
+	// Templates are the additional template definitions
+	Templates []*Template `yaml:"templates"`
+	// Overlays are the feature templates that can be applied on top of a template
+	Overlays []*Overlay `yaml:"overlays"`
+}
+
+// GetRegistryPath returns the path of the user template registry file
+func GetRegistryPath() (string, error) {
+	if path := os.Getenv(EnvRegistryFile); path != "" {
+		return path, nil
+	}
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, RegistryFileName), nil
+}
+
+// LoadRegistry reads and validates a template registry file.
+// A missing file is not an error and yields an empty registry.
+func LoadRegistry(path string) (*Registry, error) {
+	registry := &Registry{Mode: RegistryModeMerge}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return registry, nil
+		}
+		return nil, fmt.Errorf("failed to read registry file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, registry); err != nil {
+		return nil, fmt.Errorf("failed to parse registry file %s: %w", path, err)
+	}
+
+	if err := registry.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid registry file %s: %w", path, err)
+	}
+
+	return registry, nil
+}
+
+// Validate checks the registry entries and fills in defaults
+func (r *Registry) Validate() error {
+	switch r.Mode {
+	case "":
+		r.Mode = RegistryModeMerge
+	case RegistryModeMerge, RegistryModeReplace:
+	default:
+		return fmt.Errorf("unknown mode %q (expected %q or %q)", r.Mode, RegistryModeMerge, RegistryModeReplace)
+	}
+
+	seen := make(map[config.ArchitectureType]bool)
+	for i, tmpl := range r.Templates {
+		if tmpl == nil {
+			return fmt.Errorf("template #%d is empty", i+1)
+		}
+		if tmpl.Type == "" {
+			return fmt.Errorf("template #%d: type is required", i+1)
+		}
+		if tmpl.Repository == "" {
+			return fmt.Errorf("template %q: repository is required", tmpl.Type)
+		}
+		if seen[tmpl.Type] {
+			return fmt.Errorf("template %q is declared more than once", tmpl.Type)
+		}
+		seen[tmpl.Type] = true
+
+		if tmpl.Name == "" {
+			tmpl.Name = string(tmpl.Type)
+		}
+		if tmpl.Branch == "" {
+			tmpl.Branch = "main"
+		}
+		if _, err := config.ParseRefreshPolicy(tmpl.Refresh); err != nil {
+			return fmt.Errorf("template %q: %w", tmpl.Type, err)
+		}
+		if err := validateSubdir(tmpl.Subdir); err != nil {
+			return fmt.Errorf("template %q: %w", tmpl.Type, err)
+		}
+	}
+
+	seenOverlays := make(map[string]bool)
+	for i, overlay := range r.Overlays {
+		if overlay == nil {
+			return fmt.Errorf("overlay #%d is empty", i+1)
+		}
+		if overlay.Name == "" {
+			return fmt.Errorf("overlay #%d: name is required", i+1)
+		}
+		if overlay.Repository == "" {
+			return fmt.Errorf("overlay %q: repository is required", overlay.Name)
+		}
+		if seenOverlays[overlay.Name] {
+			return fmt.Errorf("overlay %q is declared more than once", overlay.Name)
+		}
+		seenOverlays[overlay.Name] = true
+
+		if overlay.Branch == "" {
+			overlay.Branch = "main"
+		}
+		if _, err := config.ParseRefreshPolicy(overlay.Refresh); err != nil {
+			return fmt.Errorf("overlay %q: %w", overlay.Name, err)
+		}
+		if err := validateSubdir(overlay.Subdir); err != nil {
+			return fmt.Errorf("overlay %q: %w", overlay.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Apply combines the registry templates with the given defaults according to the registry mode
+func (r *Registry) Apply(defaults []*Template) []*Template {
+	if r.Mode == RegistryModeReplace {
+		return r.Templates
+	}
+
+	templates := make([]*Template, 0, len(defaults)+len(r.Templates))
+	overrides := make(map[config.ArchitectureType]*Template)
+	for _, tmpl := range r.Templates {
+		overrides[tmpl.Type] = tmpl
+	}
+
+	// Keep the default ordering, swapping in registry overrides
+	for _, tmpl := range defaults {
+		if override, ok := overrides[tmpl.Type]; ok {
+			templates = append(templates, override)
+			delete(overrides, tmpl.Type)
+			continue
+		}
+		templates = append(templates, tmpl)
+	}
+
+	// Append the remaining registry templates in declaration order
+	for _, tmpl := range r.Templates {
+		if _, ok := overrides[tmpl.Type]; ok {
+			templates = append(templates, tmpl)
+		}
+	}
+
+	return templates
+}
+
+// loadTemplates returns the default templates combined with the user registry,
+// along with the overlays declared in the registry
+func loadTemplates() ([]*Template, []*Overlay, error) {
+	defaults := getDefaultTemplates()
+
+	path, err := GetRegistryPath()
+	if err != nil {
+		return defaults, nil, err
+	}
+
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		return defaults, nil, err
+	}
+
+	templates := registry.Apply(defaults)
+
+	// Make registry-only architectures known to the rest of the application
+	for _, tmpl := range templates {
+		if !tmpl.Type.IsValid() {
+			config.RegisterArchitecture(tmpl.Type, tmpl.Name, tmpl.Description)
+		}
+	}
+
+	return templates, registry.Overlays, nil
+}
@@ -0,0 +1,148 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// writeRegistry writes a registry file into a temporary directory and returns its path
+func writeRegistry(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), RegistryFileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write registry: %v", err)
+	}
+	return path
+}
+
+// TestLoadRegistryMissingFile tests that a missing registry yields an empty registry
+func TestLoadRegistryMissingFile(t *testing.T) {
+	registry, err := LoadRegistry(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadRegistry failed: %v", err)
+	}
+
+	if registry.Mode != RegistryModeMerge {
+		t.Errorf("expected mode %q, got %q", RegistryModeMerge, registry.Mode)
+	}
+	if len(registry.Templates) != 0 {
+		t.Errorf("expected no templates, got %d", len(registry.Templates))
+	}
+}
+
+// TestRegistryMerge tests merging registry templates with the defaults
+func TestRegistryMerge(t *testing.T) {
+	path := writeRegistry(t, `
+templates:
+  - type: company-layered
+    name: Company Layered
+    description: Internal layered template
+    repository: https://example.com/company/layered.git
+  - type: layered
+    repository: https://example.com/company/go-layered-fork.git
+    branch: develop
+`)
+
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadRegistry failed: %v", err)
+	}
+
+	templates := registry.Apply(getDefaultTemplates())
+	if len(templates) != 4 {
+		t.Fatalf("expected 4 templates, got %d", len(templates))
+	}
+
+	// Overridden default keeps its position
+	if templates[0].Type != config.LayeredArchitecture {
+		t.Fatalf("expected first template to be layered, got %s", templates[0].Type)
+	}
+	if templates[0].Repository != "https://example.com/company/go-layered-fork.git" {
+		t.Errorf("expected layered template to be overridden, got %s", templates[0].Repository)
+	}
+	if templates[0].Branch != "develop" {
+		t.Errorf("expected branch 'develop', got '%s'", templates[0].Branch)
+	}
+
+	// New template is appended with defaults filled in
+	custom := templates[3]
+	if custom.Type != "company-layered" {
+		t.Fatalf("expected last template to be company-layered, got %s", custom.Type)
+	}
+	if custom.Branch != "main" {
+		t.Errorf("expected default branch 'main', got '%s'", custom.Branch)
+	}
+}
+
+// TestRegistryReplace tests that replace mode drops the defaults
+func TestRegistryReplace(t *testing.T) {
+	path := writeRegistry(t, `
+mode: replace
+templates:
+  - type: company-layered
+    repository: https://example.com/company/layered.git
+`)
+
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadRegistry failed: %v", err)
+	}
+
+	templates := registry.Apply(getDefaultTemplates())
+	if len(templates) != 1 {
+		t.Fatalf("expected 1 template, got %d", len(templates))
+	}
+	if templates[0].Name != "company-layered" {
+		t.Errorf("expected name to default to type, got '%s'", templates[0].Name)
+	}
+}
+
+// TestRegistryValidation tests invalid registry files
+func TestRegistryValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "Unknown mode",
+			content: "mode: append\n",
+		},
+		{
+			name:    "Missing type",
+			content: "templates:\n  - repository: https://example.com/a.git\n",
+		},
+		{
+			name:    "Missing repository",
+			content: "templates:\n  - type: custom\n",
+		},
+		{
+			name: "Duplicate type",
+			content: `templates:
+  - type: custom
+    repository: https://example.com/a.git
+  - type: custom
+    repository: https://example.com/b.git
+`,
+		},
+		{
+			name:    "Subdir outside the repository",
+			content: "templates:\n  - type: custom\n    repository: https://example.com/a.git\n    subdir: ../other\n",
+		},
+		{
+			name:    "Absolute overlay subdir",
+			content: "overlays:\n  - name: docker\n    repository: https://example.com/a.git\n    subdir: /docker\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadRegistry(writeRegistry(t, tt.content)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
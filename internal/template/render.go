@@ -0,0 +1,114 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// TemplateFileSuffix marks files rendered with text/template during generation
+const TemplateFileSuffix = ".tmpl"
+
+// RenderData is the data passed to .tmpl files and templated paths
+type RenderData map[string]interface{}
+
+// NewRenderData builds render data from the project configuration.
+// Config fields are exposed as {{.ProjectName}}, {{.ModulePath}}, etc. and
+// template variables as {{.<name>}}. Config fields take precedence on name clashes.
+func NewRenderData(cfg *config.Config) RenderData {
+	data := make(RenderData, len(cfg.Variables)+5)
+	for name, value := range cfg.Variables {
+		data[name] = value
+	}
+
+	data["ProjectName"] = cfg.ProjectName
+	data["ModulePath"] = cfg.ModulePath
+	data["Architecture"] = cfg.Architecture.String()
+	data["Author"] = cfg.Author
+	data["Description"] = cfg.Description
+
+	return data
+}
+
+// renderFuncs are the helper functions available in templates
+var renderFuncs = texttemplate.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": strings.ReplaceAll,
+	"join": func(sep string, values []string) string {
+		return strings.Join(values, sep)
+	},
+	"has": func(values []string, value string) bool {
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	},
+}
+
+// renderString executes a text template against the render data
+func renderString(name, text string, data RenderData) (string, error) {
+	tmpl, err := texttemplate.New(name).
+		Funcs(renderFuncs).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
+
+// RenderPath expands template expressions in each element of a relative path
+// (e.g. cmd/{{.ProjectName}}/main.go). An element rendering to an empty string
+// yields an empty result, which means the path should be skipped.
+func RenderPath(relPath string, data RenderData) (string, error) {
+	if !strings.Contains(relPath, "{{") {
+		return relPath, nil
+	}
+
+	elements := strings.Split(filepath.ToSlash(relPath), "/")
+	for i, element := range elements {
+		if !strings.Contains(element, "{{") {
+			continue
+		}
+
+		rendered, err := renderString(relPath, element, data)
+		if err != nil {
+			return "", err
+		}
+
+		rendered = strings.TrimSpace(rendered)
+		if rendered == "" {
+			return "", nil
+		}
+		if strings.ContainsAny(rendered, `/\`) || rendered == "." || rendered == ".." {
+			return "", fmt.Errorf("path element %q rendered to invalid name %q", element, rendered)
+		}
+
+		elements[i] = rendered
+	}
+
+	return filepath.FromSlash(strings.Join(elements, "/")), nil
+}
+
+// renderFile renders a .tmpl file and returns its content
+func renderFile(name string, content []byte, data RenderData) ([]byte, error) {
+	rendered, err := renderString(name, string(content), data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(rendered), nil
+}
@@ -0,0 +1,147 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// TestNewRenderData tests that config fields and variables are exposed to templates
+func TestNewRenderData(t *testing.T) {
+	data := NewRenderData(&config.Config{
+		ProjectName:  "myapp",
+		ModulePath:   "github.com/user/myapp",
+		Architecture: config.LayeredArchitecture,
+		Variables: map[string]interface{}{
+			"database":    "postgres",
+			"ProjectName": "shadowed",
+		},
+	})
+
+	if data["ProjectName"] != "myapp" {
+		t.Errorf("expected config fields to take precedence, got '%v'", data["ProjectName"])
+	}
+	if data["database"] != "postgres" {
+		t.Errorf("expected variable 'database' to be 'postgres', got '%v'", data["database"])
+	}
+	if data["Architecture"] != "layered" {
+		t.Errorf("expected architecture 'layered', got '%v'", data["Architecture"])
+	}
+}
+
+// TestRenderPath tests expanding templated path elements
+func TestRenderPath(t *testing.T) {
+	data := RenderData{
+		"ProjectName": "myapp",
+		"database":    "postgres",
+		"empty":       "",
+		"bad":         "../escape",
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "Plain path",
+			path:     "internal/app/app.go",
+			expected: "internal/app/app.go",
+		},
+		{
+			name:     "Templated directory",
+			path:     "cmd/{{.ProjectName}}/main.go",
+			expected: filepath.FromSlash("cmd/myapp/main.go"),
+		},
+		{
+			name:     "Templated file name",
+			path:     "internal/db/{{.database}}.go",
+			expected: filepath.FromSlash("internal/db/postgres.go"),
+		},
+		{
+			name:     "Empty element skips path",
+			path:     "internal/{{.empty}}/file.go",
+			expected: "",
+		},
+		{
+			name:    "Element rendering to a path separator",
+			path:    "internal/{{.bad}}/file.go",
+			wantErr: true,
+		},
+		{
+			name:    "Unknown variable",
+			path:    "cmd/{{.missing}}/main.go",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RenderPath(tt.path, data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got result '%s'", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderPath failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+// TestCopyDirectoryRendersTemplates tests rendering .tmpl files and templated paths during copy
+func TestCopyDirectoryRendersTemplates(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"README.md.tmpl":                 "# {{.ProjectName}}\n\n{{.Description}} by {{.Author}}\n",
+		"cmd/{{.ProjectName}}/main.go":   "package main\n",
+		"docs/{{upper .ProjectName}}.md": "docs",
+	}
+	for name, content := range files {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	destDir := filepath.Join(t.TempDir(), "project")
+	data := NewRenderData(&config.Config{
+		ProjectName: "myapp",
+		Author:      "Jane",
+		Description: "An app",
+	})
+
+	m := &Manager{}
+	if err := m.CopyDirectoryToDestination(srcDir, destDir, data); err != nil {
+		t.Fatalf("CopyDirectoryToDestination failed: %v", err)
+	}
+
+	readme, err := os.ReadFile(filepath.Join(destDir, "README.md"))
+	if err != nil {
+		t.Fatalf("expected rendered README.md: %v", err)
+	}
+	if string(readme) != "# myapp\n\nAn app by Jane\n" {
+		t.Errorf("unexpected README.md content: %q", string(readme))
+	}
+
+	if _, err := os.Stat(filepath.Join(destDir, "README.md.tmpl")); !os.IsNotExist(err) {
+		t.Error("expected README.md.tmpl not to be copied")
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "cmd", "myapp", "main.go")); err != nil {
+		t.Errorf("expected cmd/myapp/main.go: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "docs", "MYAPP.md")); err != nil {
+		t.Errorf("expected docs/MYAPP.md: %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package template
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PickHD/pick-your-go/internal/cache"
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// source returns the cache source of the template at its Branch
+func (t *Template) source() cache.Source {
+	return cache.Source{Repository: t.Repository, Ref: t.Branch, Subdir: t.Subdir}
+}
+
+// cacheKey returns the cache entry holding the template at its Branch
+func (t *Template) cacheKey() string {
+	return t.source().Key()
+}
+
+// validateSubdir checks that a template subdirectory stays inside the repository
+func validateSubdir(subdir string) error {
+	if subdir == "" {
+		return nil
+	}
+	clean := filepath.ToSlash(filepath.Clean(subdir))
+	if filepath.IsAbs(subdir) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("subdir %q must be a directory inside the repository", subdir)
+	}
+	return nil
+}
+
+// lockedTemplate returns the template pinned to its locked commit when the lock file pins
+// its repository, together with the lock entry; otherwise the template itself
+func (m *Manager) lockedTemplate(tmpl *Template) (*Template, *LockEntry, error) {
+	entry, err := m.lockEntry(tmpl.Repository)
+	if err != nil || entry == nil {
+		return tmpl, nil, err
+	}
+
+	locked := *tmpl
+	locked.Branch = entry.Commit
+	return &locked, entry, nil
+}
+
+// Hold keeps archived cache entries extracted until the returned function is called, so that
+// the template paths returned in between stay valid. Holds nest; the extracted directories
+// are removed when the outermost hold is released.
+func (m *Manager) Hold() func() {
+	m.holds++
+	return func() {
+		if m.holds--; m.holds > 0 {
+			return
+		}
+		for _, release := range m.releases {
+			release()
+		}
+		for _, unlock := range m.readLocks {
+			unlock()
+		}
+		m.readLocks, m.extracted, m.releases = nil, nil, nil
+	}
+}
+
+// holdEntry takes the read lock of a cache entry until the enclosing Hold is released,
+// so that updates by other runs wait until the entry has been read
+func (m *Manager) holdEntry(key string) error {
+	if _, ok := m.readLocks[key]; ok {
+		return nil
+	}
+
+	unlock, err := m.cacheManager.RLockEntry(key)
+	if err != nil {
+		return err
+	}
+	if m.readLocks == nil {
+		m.readLocks = make(map[string]func())
+	}
+	m.readLocks[key] = unlock
+	return nil
+}
+
+// releaseEntry drops the read lock and the extracted files of a cache entry before this
+// manager updates it; the next read locks and extracts the updated entry
+func (m *Manager) releaseEntry(key string) {
+	if unlock, ok := m.readLocks[key]; ok {
+		unlock()
+		delete(m.readLocks, key)
+	}
+	delete(m.extracted, key)
+}
+
+// entryDir returns the directory holding the template files of a cache entry, which stays
+// read locked for the Hold the caller takes. Archived entries are extracted once per Hold.
+func (m *Manager) entryDir(key string, tmpl *Template) (string, error) {
+	if err := m.holdEntry(key); err != nil {
+		return "", err
+	}
+
+	dir, ok := m.extracted[key]
+	if !ok {
+		var release func()
+		var err error
+		dir, release, err = m.cacheManager.EntryPath(key)
+		if err != nil {
+			return "", err
+		}
+		if m.extracted == nil {
+			m.extracted = make(map[string]string)
+		}
+		m.extracted[key] = dir
+		m.releases = append(m.releases, release)
+	}
+	return filepath.Join(dir, filepath.FromSlash(tmpl.Subdir)), nil
+}
+
+// templateEntry returns the cache entry of a registry template: the entry resolved for the
+// requested version in this run, or else the entry of its branch or locked commit
+func (m *Manager) templateEntry(archType config.ArchitectureType) (string, *Template, error) {
+	tmpl, err := m.GetTemplate(archType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if key, ok := m.resolved[archType]; ok {
+		return key, tmpl, nil
+	}
+
+	locked, _, err := m.lockedTemplate(tmpl)
+	if err != nil {
+		return "", nil, err
+	}
+	return locked.cacheKey(), tmpl, nil
+}
+
+// setResolved records the cache entry a template resolved to, e.g. for a requested version
+func (m *Manager) setResolved(archType config.ArchitectureType, key string) {
+	if m.resolved == nil {
+		m.resolved = make(map[config.ArchitectureType]string)
+	}
+	m.resolved[archType] = key
+}
+
+// BundleEntries returns the cache entries to export for the named templates: every cached
+// version of each template and the cached parents they extend. Templates are named as in
+// the cache metadata, overlays as overlay-<name>; without names every cache entry is returned.
+func (m *Manager) BundleEntries(names []string) ([]string, error) {
+	defer m.Hold()()
+
+	entries, err := m.cacheManager.LookupEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range names {
+		found := false
+		for key, info := range entries {
+			if info.Template == name {
+				selected[key] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s is not cached, run 'pick-your-go templates update' first", name)
+		}
+	}
+	if len(names) == 0 {
+		for key := range entries {
+			selected[key] = true
+		}
+	}
+
+	// Parents are cache entries of their own
+	for _, key := range sortedKeys(selected) {
+		info := entries[key]
+		dir, err := m.entryDir(key, &Template{Subdir: info.Source.Subdir})
+		if err != nil {
+			return nil, err
+		}
+
+		parents, err := m.parentEntries(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s @ %s: %w", info.Template, info.Source.Ref, err)
+		}
+		for _, parentKey := range parents {
+			if _, cached := entries[parentKey]; cached {
+				selected[parentKey] = true
+			}
+		}
+	}
+
+	return sortedKeys(selected), nil
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// parentEntries returns the cache keys of the cached parents the template in templateDir extends.
+// Local parents are followed but not returned, they are not part of the cache.
+func (m *Manager) parentEntries(templateDir string) ([]string, error) {
+	var keys []string
+	dir := templateDir
+	for depth := 0; depth <= maxExtendsDepth; depth++ {
+		manifest, err := LoadManifest(dir)
+		if err != nil {
+			return nil, err
+		}
+		if manifest == nil || manifest.Extends == "" {
+			return keys, nil
+		}
+
+		parent, err := m.resolveParent(dir, manifest.Extends)
+		if err != nil {
+			return nil, err
+		}
+		if parent.template == nil {
+			dir = parent.dir
+			continue
+		}
+
+		key := parent.locked.cacheKey()
+		if !m.cacheManager.Exists(key) {
+			return keys, nil
+		}
+		keys = append(keys, key)
+		if dir, err = m.entryDir(key, parent.locked); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("template inheritance is deeper than %d levels", maxExtendsDepth)
+}
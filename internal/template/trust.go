@@ -0,0 +1,183 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TrustStoreFileName is the name of the trust store file in the config directory
+const TrustStoreFileName = "trusted.yaml"
+
+// TrustStore records the template sources whose hooks the user approved
+type TrustStore struct {
+	// Trusted lists the approved template sources
+	Trusted []TrustEntry `yaml:"trusted"`
+
+	path string
+}
+
+// TrustEntry is an approved template source at a specific commit
+type TrustEntry struct {
+	// Repository is the template repository URL (or local directory)
+	Repository string `yaml:"repository"`
+	// Commit is the approved commit (or hook fingerprint for local templates)
+	Commit string `yaml:"commit"`
+}
+
+// TemplateSource identifies the exact template content whose hooks are about to run
+type TemplateSource struct {
+	// Repository is the repository URL or the absolute local directory
+	Repository string
+	// Commit is the cached commit, or a fingerprint of the hooks for local templates
+	Commit string
+}
+
+// LoadTrustStore reads the trust store from the user config directory.
+// A missing file yields an empty store.
+func LoadTrustStore() (*TrustStore, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadTrustStoreFile(filepath.Join(configDir, TrustStoreFileName))
+}
+
+// LoadTrustStoreFile reads a trust store from the given file
+func LoadTrustStoreFile(path string) (*TrustStore, error) {
+	store := &TrustStore{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read trust store: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse trust store %s: %w", path, err)
+	}
+
+	return store, nil
+}
+
+// IsTrusted reports whether the source was approved at exactly this commit
+func (s *TrustStore) IsTrusted(source TemplateSource) bool {
+	if source.Commit == "" {
+		// Without a commit the content cannot be pinned, so it is never trusted
+		return false
+	}
+
+	for _, entry := range s.Trusted {
+		if entry.Repository == source.Repository && entry.Commit == source.Commit {
+			return true
+		}
+	}
+	return false
+}
+
+// Trust approves the source at its commit, replacing an earlier approval of the same repository
+func (s *TrustStore) Trust(source TemplateSource) {
+	for i, entry := range s.Trusted {
+		if entry.Repository == source.Repository {
+			s.Trusted[i].Commit = source.Commit
+			return
+		}
+	}
+
+	s.Trusted = append(s.Trusted, TrustEntry{
+		Repository: source.Repository,
+		Commit:     source.Commit,
+	})
+}
+
+// Save writes the trust store to disk
+func (s *TrustStore) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trust store: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write trust store: %w", err)
+	}
+
+	return nil
+}
+
+// hooksFingerprint hashes hook commands so that changed hooks of a local template need new approval
+func hooksFingerprint(hooks []config.Hook) string {
+	hash := sha256.New()
+	for _, hook := range hooks {
+		hash.Write([]byte(hook.Name))
+		hash.Write([]byte{0})
+		hash.Write([]byte(hook.Run))
+		hash.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// GetTemplateSource returns the source of a cached template at its cached commit
+func (m *Manager) GetTemplateSource(archType config.ArchitectureType) (TemplateSource, error) {
+	defer m.Hold()()
+
+	tmpl, err := m.GetTemplate(archType)
+	if err != nil {
+		return TemplateSource{}, err
+	}
+
+	key, _, err := m.templateEntry(archType)
+	if err != nil {
+		return TemplateSource{}, err
+	}
+
+	source := TemplateSource{Repository: tmpl.Repository}
+	if info, err := m.cacheManager.GetCacheInfo(key); err == nil {
+		source.Commit = info.Commit
+	}
+
+	// Inherited hooks come from parents that may move independently of the commit
+	if source.Commit != "" {
+		cachePath, err := m.entryDir(key, tmpl)
+		if err != nil {
+			return TemplateSource{}, err
+		}
+		if chain, err := m.GetTemplateChain(cachePath); err == nil && len(chain) > 1 {
+			manifest, err := m.loadChainManifest(cachePath)
+			if err != nil {
+				return TemplateSource{}, err
+			}
+			if manifest != nil {
+				source.Commit += "+" + hooksFingerprint(manifest.Hooks)
+			}
+		}
+	}
+
+	return source, nil
+}
+
+// GetDirectorySource returns the source of a local template directory.
+// The hooks fingerprint stands in for the commit.
+func GetDirectorySource(templateDir string, hooks []config.Hook) (TemplateSource, error) {
+	absDir, err := filepath.Abs(templateDir)
+	if err != nil {
+		return TemplateSource{}, fmt.Errorf("failed to resolve template directory: %w", err)
+	}
+
+	return TemplateSource{
+		Repository: absDir,
+		Commit:     hooksFingerprint(hooks),
+	}, nil
+}
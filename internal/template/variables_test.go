@@ -0,0 +1,159 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testVariablesManifest returns a manifest declaring one variable of each type
+func testVariablesManifest(t *testing.T) *Manifest {
+	t.Helper()
+
+	manifest, err := ParseManifest([]byte(`
+variables:
+  - name: service_name
+    validate: "^[a-z][a-z0-9-]*$"
+    default: api
+  - name: metrics
+    type: bool
+  - name: cache
+    type: select
+    options: [none, redis, memcached]
+  - name: features
+    type: multiselect
+    options: [docker, ci, otel]
+    default: [ci]
+`))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	return manifest
+}
+
+// TestParseSetFlags tests parsing --set key=value pairs
+func TestParseSetFlags(t *testing.T) {
+	values, err := ParseSetFlags([]string{"cache=redis", "features=docker,otel", "empty="})
+	if err != nil {
+		t.Fatalf("ParseSetFlags failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"cache":    "redis",
+		"features": "docker,otel",
+		"empty":    "",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	for _, invalid := range []string{"novalue", "=value"} {
+		if _, err := ParseSetFlags([]string{invalid}); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+// TestResolveVariablesDefaults tests that unset variables get their defaults
+func TestResolveVariablesDefaults(t *testing.T) {
+	manifest := testVariablesManifest(t)
+
+	resolved, err := manifest.ResolveVariables(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("ResolveVariables failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"service_name": "api",
+		"metrics":      false,
+		"cache":        "none",
+		"features":     []string{"ci"},
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("expected %v, got %v", expected, resolved)
+	}
+}
+
+// TestResolveVariablesConversion tests converting values from --set and --values
+func TestResolveVariablesConversion(t *testing.T) {
+	manifest := testVariablesManifest(t)
+
+	resolved, err := manifest.ResolveVariables(map[string]interface{}{
+		"service_name": "billing",
+		"metrics":      "true",
+		"cache":        "redis",
+		"features":     []interface{}{"otel", "docker"},
+		"extra":        "passed through",
+	})
+	if err != nil {
+		t.Fatalf("ResolveVariables failed: %v", err)
+	}
+
+	if resolved["metrics"] != true {
+		t.Errorf("expected metrics to be true, got %v", resolved["metrics"])
+	}
+	if !reflect.DeepEqual(resolved["features"], []string{"docker", "otel"}) {
+		t.Errorf("expected features in option order, got %v", resolved["features"])
+	}
+	if resolved["extra"] != "passed through" {
+		t.Errorf("expected undeclared variable to be passed through, got %v", resolved["extra"])
+	}
+}
+
+// TestResolveVariablesInvalid tests validation of variable values
+func TestResolveVariablesInvalid(t *testing.T) {
+	manifest := testVariablesManifest(t)
+
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+	}{
+		{"Pattern mismatch", map[string]interface{}{"service_name": "Billing"}},
+		{"Not a boolean", map[string]interface{}{"metrics": "maybe"}},
+		{"Unknown select option", map[string]interface{}{"cache": "dynamo"}},
+		{"Unknown multiselect option", map[string]interface{}{"features": "docker,k8s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := manifest.ResolveVariables(tt.values); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestLoadValuesFile tests reading variable values from YAML
+func TestLoadValuesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(path, []byte("cache: redis\nmetrics: true\nfeatures: [docker]\n"), 0644); err != nil {
+		t.Fatalf("failed to write values file: %v", err)
+	}
+
+	values, err := LoadValuesFile(path)
+	if err != nil {
+		t.Fatalf("LoadValuesFile failed: %v", err)
+	}
+
+	resolved, err := testVariablesManifest(t).ResolveVariables(values)
+	if err != nil {
+		t.Fatalf("ResolveVariables failed: %v", err)
+	}
+	if resolved["cache"] != "redis" || resolved["metrics"] != true {
+		t.Errorf("unexpected resolved values: %v", resolved)
+	}
+}
+
+// TestUnsetVariables tests finding variables without a value
+func TestUnsetVariables(t *testing.T) {
+	unset := testVariablesManifest(t).UnsetVariables(map[string]interface{}{"cache": "redis"})
+	if len(unset) != 3 {
+		t.Fatalf("expected 3 unset variables, got %d", len(unset))
+	}
+	for _, v := range unset {
+		if v.Name == "cache" {
+			t.Error("expected 'cache' not to be unset")
+		}
+	}
+}
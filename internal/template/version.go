@@ -0,0 +1,323 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// commitSHAPattern matches full and abbreviated commit SHAs
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// fullCommitSHAPattern matches full commit SHAs only
+var fullCommitSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// isCommitSHA reports whether a ref looks like a commit SHA. Branches and tags may be named
+// like one too (e.g. 20241016), a branch or tag of that name takes precedence over the commit.
+func isCommitSHA(ref string) bool {
+	return commitSHAPattern.MatchString(ref)
+}
+
+// isFullCommitSHA reports whether a ref is a full, unabbreviated commit SHA
+func isFullCommitSHA(ref string) bool {
+	return fullCommitSHAPattern.MatchString(ref)
+}
+
+// EnsureTemplateVersionCached ensures a template is cached at the requested version,
+// downloading it when the cache is missing, expired or holds another version.
+// The version is a tag, a commit SHA or a semver range (e.g. ^1.2) matched against the
+// repository tags; an empty version means the template branch, or the locked commit
+// when the lock file pins the template repository.
+func (m *Manager) EnsureTemplateVersionCached(archType config.ArchitectureType, version, token string) error {
+	defer m.Hold()()
+
+	tmpl, err := m.GetTemplate(archType)
+	if err != nil {
+		return fmt.Errorf("failed to get template: %w", err)
+	}
+
+	if version == "" {
+		key, err := m.ensureCached(tmpl, token)
+		if err != nil {
+			return err
+		}
+		m.setResolved(archType, key)
+		dir, err := m.entryDir(key, tmpl)
+		if err != nil {
+			return err
+		}
+		return m.EnsureParentsCached(dir, token)
+	}
+
+	entry, err := m.lockEntry(tmpl.Repository)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		return fmt.Errorf("%s is locked to commit %s in %s, run 'pick-your-go templates lock --update %s' to change it",
+			tmpl.Repository, entry.Commit, LockFileName, archType)
+	}
+
+	ref, err := m.ResolveVersion(tmpl, version, token)
+	if err != nil {
+		return err
+	}
+
+	// Each version has its own cache entry, next to the entry of the branch
+	pinned := *tmpl
+	pinned.Branch = ref
+	if !m.cacheManager.Exists(pinned.cacheKey()) {
+		fmt.Fprintf(m.out(), "Using %s version %s\n", tmpl.Name, ref)
+	}
+
+	key, err := m.ensureCached(&pinned, token)
+	if err != nil {
+		return err
+	}
+	m.setResolved(archType, key)
+
+	dir, err := m.entryDir(key, &pinned)
+	if err != nil {
+		return err
+	}
+	return m.EnsureParentsCached(dir, token)
+}
+
+// ResolveVersion resolves a version to the ref to clone.
+// Tags are matched exactly first, then the version is read as a semver range
+// and the highest matching tag wins. In offline mode the tags of the cached clone are used.
+func (m *Manager) ResolveVersion(tmpl *Template, version, token string) (string, error) {
+	if version == "" {
+		return tmpl.Branch, nil
+	}
+
+	var tags []string
+	var err error
+	if m.offline {
+		tags, err = m.listCachedTags(tmpl)
+	} else {
+		tags, err = m.listRemoteTags(tmpl.Repository, token)
+	}
+	// A version looking like a commit SHA is a commit unless a tag has that name
+	if isCommitSHA(version) && (err != nil || !slices.Contains(tags, version)) {
+		return version, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return matchVersion(tags, version)
+}
+
+// matchVersion returns the tag equal to version, or the highest tag within the semver range version
+func matchVersion(tags []string, version string) (string, error) {
+	for _, tag := range tags {
+		if tag == version {
+			return tag, nil
+		}
+	}
+
+	constraint, err := semver.NewConstraint(version)
+	if err != nil {
+		return "", fmt.Errorf("version %q is neither a tag, a commit SHA nor a semver range", version)
+	}
+
+	var best *semver.Version
+	var bestTag string
+	for _, tag := range tags {
+		v, err := semver.NewVersion(tag)
+		if err != nil {
+			// Not a version tag
+			continue
+		}
+		if constraint.Check(v) && (best == nil || v.GreaterThan(best)) {
+			best, bestTag = v, tag
+		}
+	}
+
+	if best == nil {
+		return "", fmt.Errorf("no tag matches version %q", version)
+	}
+
+	return bestTag, nil
+}
+
+// listRemoteTags lists the tag names of a repository without cloning it
+func (m *Manager) listRemoteTags(repository, token string) ([]string, error) {
+	refs, err := m.gitBackend().lsRemote(repository, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", repository, err)
+	}
+
+	var tags []string
+	for name := range refs {
+		// Skip the peeled entries of annotated tags
+		if tag, ok := strings.CutPrefix(name, "refs/tags/"); ok && !strings.HasSuffix(tag, "^{}") {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	return tags, nil
+}
+
+// listCachedTags lists the tag names known to the cached clones of the template repository,
+// at any ref. Clones fetched at different times know different tags, the tags of all are merged.
+// Archived entries keep no git history; without a clone the refs of the archived versions are used.
+func (m *Manager) listCachedTags(tmpl *Template) ([]string, error) {
+	entries, err := m.cacheManager.LookupEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags, archivedRefs []string
+	seen := make(map[string]bool)
+	cloned := false
+	for key, info := range entries {
+		if info.Source.Repository != tmpl.Repository || !m.cacheManager.Exists(key) {
+			continue
+		}
+		clonePath, ok := m.cacheManager.EntryClone(key)
+		if !ok {
+			archivedRefs = append(archivedRefs, info.Source.Ref)
+			continue
+		}
+
+		cloneTags, err := m.gitBackend().tags(clonePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cached tags of %s: %w", tmpl.Name, err)
+		}
+		cloned = true
+		for _, tag := range cloneTags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	if cloned {
+		sort.Strings(tags)
+		return tags, nil
+	}
+
+	if len(archivedRefs) > 0 {
+		fmt.Fprintf(m.out(), "Resolving %s against its cached versions, compressed cache entries keep no git history\n", tmpl.Name)
+		sort.Strings(archivedRefs)
+		return archivedRefs, nil
+	}
+
+	return nil, fmt.Errorf("%s is not cached, template versions cannot be resolved in offline mode", tmpl.Name)
+}
+
+// remoteCommit returns the commit a branch or tag of a repository points to without cloning it.
+// A ref looking like a commit SHA that names no branch or tag is returned as is.
+func (m *Manager) remoteCommit(repository, ref, token string) (string, error) {
+	refs, err := m.gitBackend().lsRemote(repository, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to check %s: %w", repository, err)
+	}
+
+	// Annotated tags are listed twice, the peeled entry holds the tagged commit
+	for _, name := range []string{"refs/tags/" + ref + "^{}", "refs/heads/" + ref, "refs/tags/" + ref} {
+		if commit, ok := refs[name]; ok {
+			return commit, nil
+		}
+	}
+	if isCommitSHA(ref) {
+		return ref, nil
+	}
+
+	return "", fmt.Errorf("ref %s not found in %s", ref, repository)
+}
+
+// refreshedEntries returns the cache entries already checked against their remote by the manager
+// and its clones, so that resolving the manifest and generating the project check the remote only once
+func (m *Manager) refreshedEntries() *sync.Map {
+	if m.refreshed == nil {
+		m.refreshed = &sync.Map{}
+	}
+	return m.refreshed
+}
+
+// refreshCached downloads a cached template again only when its ref points to another commit upstream.
+// When the remote cannot be reached the cached copy is used.
+func (m *Manager) refreshCached(key string, tmpl *Template, token string) error {
+	if _, done := m.refreshedEntries().LoadOrStore(key, true); done {
+		return nil
+	}
+
+	available, err := m.remoteChanged(key, tmpl.Repository, tmpl.Branch, token)
+	if err != nil {
+		fmt.Fprintf(m.out(), "Warning: failed to check %s for updates, using the cached copy: %v\n", tmpl.Name, err)
+		return nil
+	}
+	if available {
+		if err := m.updateTemplate(tmpl, token); err != nil {
+			// The previous copy is still in the cache, an outdated template beats no template
+			fmt.Fprintf(m.out(), "Warning: failed to update %s, using the stale cached copy: %v\n", tmpl.Name, err)
+		}
+		return nil
+	}
+
+	return m.cacheManager.UpdateCheckTime(key)
+}
+
+// IsUpdateAvailable checks whether the ref a template is cached at points to a newer commit upstream
+func (m *Manager) IsUpdateAvailable(archType config.ArchitectureType, token string) (bool, error) {
+	key, tmpl, err := m.templateEntry(archType)
+	if err != nil {
+		return false, err
+	}
+	return m.updateAvailable(key, tmpl, token)
+}
+
+// updateAvailable checks a cached template against the lock file or, when it is not locked,
+// against the remote commit of the ref it is cached at
+func (m *Manager) updateAvailable(key string, tmpl *Template, token string) (bool, error) {
+	info, err := m.cacheManager.GetCacheInfo(key)
+	if err != nil {
+		return false, err
+	}
+
+	entry, err := m.lockEntry(tmpl.Repository)
+	if err != nil {
+		return false, err
+	}
+	if entry != nil {
+		return info.Commit != entry.Commit, nil
+	}
+
+	ref := info.Source.Ref
+	if ref == "" {
+		ref = tmpl.Branch
+	}
+	if m.offline {
+		return false, nil
+	}
+	return m.remoteChanged(key, tmpl.Repository, ref, token)
+}
+
+// remoteChanged compares the commit of a cache entry with the commit ref points to upstream
+func (m *Manager) remoteChanged(key string, repository, ref, token string) (bool, error) {
+	info, err := m.cacheManager.GetCacheInfo(key)
+	if err != nil {
+		return false, err
+	}
+
+	remote, err := m.remoteCommit(repository, ref, token)
+	if err != nil {
+		return false, err
+	}
+
+	// Entries cached before commits were recorded cannot be compared. A commit SHA the ref
+	// resolved to as is may be abbreviated, and never changes.
+	return info.Commit == "" || !strings.HasPrefix(info.Commit, remote), nil
+}
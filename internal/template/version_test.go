@@ -0,0 +1,322 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PickHD/pick-your-go/internal/config"
+)
+
+// TestMatchVersion tests resolving exact tags and semver ranges against tags
+func TestMatchVersion(t *testing.T) {
+	tags := []string{"v1.1.0", "v1.2.0", "v1.2.5", "v1.3.0-rc1", "v2.0.0", "latest"}
+
+	tests := []struct {
+		version  string
+		expected string
+		wantErr  bool
+	}{
+		{"v1.2.0", "v1.2.0", false},
+		{"latest", "latest", false},
+		{"^1.2", "v1.2.5", false},
+		{"~1.1", "v1.1.0", false},
+		{">=1.0.0 <2.0.0", "v1.2.5", false},
+		{"^2", "v2.0.0", false},
+		{"^3", "", true},
+		{"not a version", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := matchVersion(tags, tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("matchVersion(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("matchVersion(%q) = %q, expected %q", tt.version, got, tt.expected)
+		}
+	}
+}
+
+// TestIsCommitSHA tests telling commit SHAs apart from branch and tag names
+func TestIsCommitSHA(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected bool
+	}{
+		{"8f5a4a433a84dffa4c4b4c4c40353d113188a242", true},
+		{"8f5a4a4", true},
+		{"8f5a4a", false},
+		{"main", false},
+		{"v1.2.0", false},
+		{"8F5A4A433A84", false},
+	}
+
+	for _, tt := range tests {
+		if got := isCommitSHA(tt.ref); got != tt.expected {
+			t.Errorf("isCommitSHA(%q) = %v, expected %v", tt.ref, got, tt.expected)
+		}
+	}
+}
+
+// TestEnsureCachedChecksRemote tests that a cached template is downloaded again only when its branch moved
+func TestEnsureCachedChecksRemote(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main // one\n"})
+
+	manager := newTestManager(t)
+	tmpl := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "main"}
+	manager.cacheManager.SetRefreshPolicy(string(tmpl.Type), config.RefreshPolicy{})
+
+	key, err := manager.ensureCached(tmpl, "")
+	if err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+	if available, err := manager.updateAvailable(key, tmpl, ""); err != nil || available {
+		t.Errorf("expected no update right after caching, got %v (%v)", available, err)
+	}
+
+	commitTestRepo(t, repo, map[string]string{"main.go": "package main // two\n"})
+	if available, err := manager.updateAvailable(key, tmpl, ""); err != nil || !available {
+		t.Errorf("expected an update after a new commit, got %v (%v)", available, err)
+	}
+
+	manager.refreshedEntries().Clear()
+	if _, err := manager.ensureCached(tmpl, ""); err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+	info, err := manager.cacheManager.GetCacheInfo(key)
+	if err != nil {
+		t.Fatalf("GetCacheInfo failed: %v", err)
+	}
+	if head := gitRun(t, repo, "rev-parse", "HEAD"); info.Commit != head {
+		t.Errorf("expected the cache to move to %s, got %s", head, info.Commit)
+	}
+}
+
+// TestEnsureCachedHexNamedBranch tests that a branch named like a commit SHA is followed upstream
+func TestEnsureCachedHexNamedBranch(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main // one\n"})
+	gitRun(t, repo, "checkout", "--quiet", "-b", "20241016")
+
+	manager := newTestManager(t)
+	tmpl := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "20241016"}
+	manager.cacheManager.SetRefreshPolicy(string(tmpl.Type), config.RefreshPolicy{})
+
+	key, err := manager.ensureCached(tmpl, "")
+	if err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+
+	commitTestRepo(t, repo, map[string]string{"main.go": "package main // two\n"})
+	if available, err := manager.updateAvailable(key, tmpl, ""); err != nil || !available {
+		t.Errorf("expected an update after a new commit on the branch, got %v (%v)", available, err)
+	}
+
+	manager.refreshedEntries().Clear()
+	if _, err := manager.ensureCached(tmpl, ""); err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+	info, err := manager.cacheManager.GetCacheInfo(key)
+	if err != nil {
+		t.Fatalf("GetCacheInfo failed: %v", err)
+	}
+	if head := gitRun(t, repo, "rev-parse", "HEAD"); info.Commit != head {
+		t.Errorf("expected the cache to move to %s, got %s", head, info.Commit)
+	}
+}
+
+// TestEnsureCachedOffline tests that offline mode uses the cache without contacting the remote
+func TestEnsureCachedOffline(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main\n"})
+
+	manager := newTestManager(t)
+	tmpl := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "main"}
+	key, err := manager.ensureCached(tmpl, "")
+	if err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+
+	// The remote is gone, offline mode must not notice
+	if err := os.RemoveAll(repo); err != nil {
+		t.Fatal(err)
+	}
+	manager.SetOffline(true)
+	manager.refreshedEntries().Clear()
+
+	if _, err := manager.ensureCached(tmpl, ""); err != nil {
+		t.Errorf("expected the cached template to be used offline, got %v", err)
+	}
+	if available, err := manager.updateAvailable(key, tmpl, ""); err != nil || available {
+		t.Errorf("expected no update check offline, got %v (%v)", available, err)
+	}
+
+	missing := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "develop"}
+	if _, err := manager.ensureCached(missing, ""); err == nil {
+		t.Errorf("expected an error for a ref that is not cached")
+	}
+}
+
+// TestResolveVersionOfflineMergesClones tests that offline versions resolve against the tags
+// of every cached clone, not of whichever clone is found first
+func TestResolveVersionOfflineMergesClones(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main\n"})
+	gitRun(t, repo, "tag", "v1.0.0")
+
+	manager := newTestManager(t)
+	tmpl := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "main"}
+	if _, err := manager.ensureCached(tmpl, ""); err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+
+	// The branch clone does not know the newer tag
+	commitTestRepo(t, repo, map[string]string{"main.go": "package main // two\n"})
+	gitRun(t, repo, "tag", "v1.2.0")
+	if _, err := manager.ensureCached(&Template{Type: "demo", Name: "demo", Repository: repo, Branch: "v1.0.0"}, ""); err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+
+	manager.SetOffline(true)
+	for i := 0; i < 10; i++ {
+		if ref, err := manager.ResolveVersion(tmpl, "^1.0", ""); err != nil || ref != "v1.2.0" {
+			t.Fatalf("expected v1.2.0 from the merged tags, got %s (%v)", ref, err)
+		}
+	}
+}
+
+// TestResolveVersionArchivedOffline tests that offline versions of a template cached only
+// as archives resolve against its cached versions
+func TestResolveVersionArchivedOffline(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main\n"})
+	gitRun(t, repo, "tag", "v1.0.0")
+	commitTestRepo(t, repo, map[string]string{"main.go": "package main // two\n"})
+	gitRun(t, repo, "tag", "v1.1.0")
+	gitRun(t, repo, "tag", "v1.2.0")
+
+	manager := newArchivingTestManager(t, repo)
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		if err := manager.EnsureTemplateVersionCached("demo", version, ""); err != nil {
+			t.Fatalf("EnsureTemplateVersionCached failed: %v", err)
+		}
+	}
+
+	manager.SetOffline(true)
+	tmpl, _ := manager.GetTemplate("demo")
+	if ref, err := manager.ResolveVersion(tmpl, "^1.0", ""); err != nil || ref != "v1.1.0" {
+		t.Errorf("expected the highest cached version v1.1.0, got %s (%v)", ref, err)
+	}
+}
+
+// TestEnsureCachedRefreshNever tests that a template with the never refresh policy is not checked for updates
+func TestEnsureCachedRefreshNever(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main // one\n"})
+
+	manager := newTestManager(t)
+	tmpl := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "main"}
+	manager.cacheManager.SetRefreshPolicy(string(tmpl.Type), config.RefreshPolicy{Never: true})
+
+	key, err := manager.ensureCached(tmpl, "")
+	if err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+	first, _ := manager.cacheManager.GetCacheInfo(key)
+
+	commitTestRepo(t, repo, map[string]string{"main.go": "package main // two\n"})
+	manager.refreshedEntries().Clear()
+	if _, err := manager.ensureCached(tmpl, ""); err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+
+	if info, _ := manager.cacheManager.GetCacheInfo(key); info.Commit != first.Commit {
+		t.Errorf("expected the cached commit %s to be kept, got %s", first.Commit, info.Commit)
+	}
+}
+
+// TestEnsureCachedKeysByRef tests that two refs of the same repository are cached side by side
+func TestEnsureCachedKeysByRef(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main // one\n"})
+	gitRun(t, repo, "tag", "v1.0.0")
+	commitTestRepo(t, repo, map[string]string{"main.go": "package main // two\n"})
+
+	manager := newTestManager(t)
+	branch := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "main"}
+	tagged := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "v1.0.0"}
+
+	branchKey, err := manager.ensureCached(branch, "")
+	if err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+	taggedKey, err := manager.ensureCached(tagged, "")
+	if err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+	if branchKey == taggedKey {
+		t.Fatalf("expected separate cache entries for main and v1.0.0")
+	}
+
+	for key, expected := range map[string]string{branchKey: "package main // two\n", taggedKey: "package main // one\n"} {
+		data, _ := os.ReadFile(filepath.Join(manager.cacheManager.GetTemplateCachePath(key), "main.go"))
+		if string(data) != expected {
+			t.Errorf("entry %s holds main.go = %q, expected %q", key, data, expected)
+		}
+	}
+
+	info, err := manager.cacheManager.GetCacheInfo(taggedKey)
+	if err != nil {
+		t.Fatalf("GetCacheInfo failed: %v", err)
+	}
+	if info.Template != "demo" || info.Source.Repository != repo || info.Source.Ref != "v1.0.0" {
+		t.Errorf("unexpected metadata for the tagged entry: %+v", info)
+	}
+}
+
+// TestEnsureCachedRepairsCorruptedEntry tests that edited or deleted cache files are restored before use
+func TestEnsureCachedRepairsCorruptedEntry(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main\n", "README.md": "# demo\n"})
+
+	manager := newTestManager(t)
+	tmpl := &Template{Type: "demo", Name: "demo", Repository: repo, Branch: "main"}
+	manager.cacheManager.SetRefreshPolicy(string(tmpl.Type), config.RefreshPolicy{Never: true})
+
+	key, err := manager.ensureCached(tmpl, "")
+	if err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+
+	cachePath := manager.cacheManager.GetTemplateCachePath(key)
+	writeFiles(t, cachePath, map[string]string{"main.go": "package broken\n", "extra.txt": "extra\n"})
+	if err := os.Remove(filepath.Join(cachePath, "README.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Offline the entry cannot be repaired and must not be used
+	manager.SetOffline(true)
+	if _, err := manager.ensureCached(tmpl, ""); err == nil {
+		t.Errorf("expected a corrupted entry to fail offline")
+	}
+
+	manager.SetOffline(false)
+	if _, err := manager.ensureCached(tmpl, ""); err != nil {
+		t.Fatalf("ensureCached failed: %v", err)
+	}
+	if report, err := manager.cacheManager.VerifyEntry(key); err != nil || !report.OK() {
+		t.Errorf("expected the entry to be repaired, got %v (%v)", report, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(cachePath, "main.go")); string(data) != "package main\n" {
+		t.Errorf("main.go = %q after repair", data)
+	}
+}
+
+// TestRefreshedEntriesShared tests that the entries checked against their remote are shared
+// with clones but not between managers
+func TestRefreshedEntriesShared(t *testing.T) {
+	manager := newTestManager(t)
+	manager.refreshedEntries().Store("demo", true)
+
+	if _, ok := manager.Clone().refreshedEntries().Load("demo"); !ok {
+		t.Errorf("expected a clone to share the checked entries")
+	}
+	if _, ok := newTestManager(t).refreshedEntries().Load("demo"); ok {
+		t.Errorf("expected another manager to check the entry again")
+	}
+}
@@ -3,12 +3,16 @@ package ui
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/PickHD/pick-your-go/internal/config"
+	"github.com/PickHD/pick-your-go/internal/template"
 
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
 )
 
 // Styles for the UI
@@ -86,8 +90,11 @@ type InitFormData struct {
 	OutputDir    string
 }
 
-// RunInitForm runs the interactive initialization form
-func RunInitForm(archType, name, module, output, author, description string) (*config.Config, error) {
+// RunInitForm runs the interactive initialization form.
+// The architecture options are built from the available templates, and a group is
+// added for each template variable that has no value yet. Collected variable
+// values are added to values.
+func RunInitForm(templates []*template.Template, variables []template.Variable, values map[string]interface{}, archType, name, module, output, author, description string) (*config.Config, error) {
 	// Show logo at the beginning
 	ShowLogo()
 
@@ -101,14 +108,13 @@ func RunInitForm(archType, name, module, output, author, description string) (*c
 	}
 
 	// Architecture selection options
-	archOptions := []huh.Option[string]{
-		huh.NewOption("Layered Architecture - Traditional layered architecture", config.LayeredArchitecture.String()),
-		huh.NewOption("Modular Architecture - Modular monolith with DDD", config.ModularArchitecture.String()),
-		huh.NewOption("Hexagonal Architecture - Ports and adapters pattern", config.HexagonalArchitecture.String()),
+	archOptions := make([]huh.Option[string], 0, len(templates))
+	for _, tmpl := range templates {
+		archOptions = append(archOptions, huh.NewOption(archOptionLabel(tmpl), tmpl.Type.String()))
 	}
 
 	// Create form
-	form := huh.NewForm(
+	groups := []*huh.Group{
 		huh.NewGroup(
 			huh.NewSelect[string]().
 				Title("Choose your architecture pattern").
@@ -155,7 +161,13 @@ func RunInitForm(archType, name, module, output, author, description string) (*c
 				Lines(3).
 				Value(&formData.Description),
 		),
-	)
+	}
+
+	// Template variables that still need a value
+	variableGroups, variableFields := buildVariableGroups(variables)
+	groups = append(groups, variableGroups...)
+
+	form := huh.NewForm(groups...)
 
 	// Set default values if provided
 	if output == "" {
@@ -167,6 +179,8 @@ func RunInitForm(archType, name, module, output, author, description string) (*c
 		return nil, fmt.Errorf("form error: %w", err)
 	}
 
+	collectVariableValues(variableFields, values)
+
 	// Convert to config
 	cfg := &config.Config{
 		Architecture: config.ArchitectureType(formData.Architecture),
@@ -175,11 +189,29 @@ func RunInitForm(archType, name, module, output, author, description string) (*c
 		OutputDir:    formData.OutputDir,
 		Author:       formData.Author,
 		Description:  formData.Description,
+		Variables:    values,
 	}
 
 	return cfg, nil
 }
 
+// archOptionLabel returns the label shown for a template in the architecture selection
+func archOptionLabel(tmpl *template.Template) string {
+	switch tmpl.Type {
+	case config.LayeredArchitecture:
+		return "Layered Architecture - Traditional layered architecture"
+	case config.ModularArchitecture:
+		return "Modular Architecture - Modular monolith with DDD"
+	case config.HexagonalArchitecture:
+		return "Hexagonal Architecture - Ports and adapters pattern"
+	default:
+		if tmpl.Description == "" {
+			return tmpl.Name
+		}
+		return fmt.Sprintf("%s - %s", tmpl.Name, tmpl.Description)
+	}
+}
+
 // ShowLogo displays the "PICK YOUR GO" logo at the top of the form
 func ShowLogo() {
 	// Calculate terminal width for centering
@@ -235,6 +267,18 @@ func ShowSummary(cfg *config.Config) {
 	printSummaryRow("Architecture:", cfg.Architecture.DisplayName())
 	printSummaryRow("Output Directory:", cfg.OutputDir)
 
+	if cfg.TemplateDir != "" {
+		printSummaryRow("Template Directory:", cfg.TemplateDir)
+	}
+
+	if cfg.TemplateVersion != "" {
+		printSummaryRow("Template Version:", cfg.TemplateVersion)
+	}
+
+	if len(cfg.Overlays) > 0 {
+		printSummaryRow("Overlays:", strings.Join(cfg.Overlays, ", "))
+	}
+
 	if cfg.Author != "" {
 		printSummaryRow("Author:", cfg.Author)
 	}
@@ -243,10 +287,28 @@ func ShowSummary(cfg *config.Config) {
 		printSummaryRow("Description:", cfg.Description)
 	}
 
+	// Template variables in a stable order
+	names := make([]string, 0, len(cfg.Variables))
+	for name := range cfg.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		printSummaryRow(name+":", formatVariableValue(cfg.Variables[name]))
+	}
+
 	printSummaryRow("Project Path:", cfg.GetProjectPath())
 	fmt.Println()
 }
 
+// formatVariableValue formats a template variable value for display
+func formatVariableValue(value interface{}) string {
+	if list, ok := value.([]string); ok {
+		return strings.Join(list, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
 // printSummaryRow prints a single row of the summary with proper alignment
 func printSummaryRow(label, value string) {
 	// Render label with fixed width (right-aligned by default with Width())
@@ -275,34 +337,62 @@ func ConfirmGeneration() (bool, error) {
 	return confirm, nil
 }
 
-// ShowSuccess displays success message
-func ShowSuccess(cfg *config.Config) {
+// ConfirmHookTrust lists the hooks of an untrusted template and asks whether to run them
+func ConfirmHookTrust(repository string, hooks []config.Hook) (bool, error) {
+	fmt.Println()
+	fmt.Println(WarningStyle.Render("⚠ This template wants to run the following commands:"))
+	fmt.Printf("  Source: %s\n\n", repository)
+	for _, hook := range hooks {
+		fmt.Printf("  $ %s\n", hook.Run)
+	}
+	fmt.Println()
+
+	var confirm bool
+
+	confirmForm := huh.NewConfirm().
+		Title("Trust this template and run its hooks?").
+		Description("Your answer is remembered until the template changes.").
+		Value(&confirm)
+
+	if err := confirmForm.Run(); err != nil {
+		return false, err
+	}
+
+	return confirm, nil
+}
+
+// IsInteractive reports whether stdin is a terminal that can answer prompts
+func IsInteractive() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// ShowSuccess displays success message with the next steps and notes declared by the template manifest
+func ShowSuccess(cfg *config.Config, manifest *template.Manifest) {
 	fmt.Println()
 	fmt.Println(SuccessStyle.Render("✓ Project generated successfully!"))
 	fmt.Println()
 
+	steps := []string{fmt.Sprintf("cd %s", cfg.GetProjectPath())}
+	if cfg.NoHooks {
+		// Hooks were skipped, list them so they can be run by hand
+		for _, hook := range manifest.Hooks {
+			steps = append(steps, fmt.Sprintf("Run: %s", hook.Run))
+		}
+	}
+	steps = append(steps, manifest.NextSteps...)
+
 	fmt.Println("Next steps:")
-	fmt.Printf("  1. cd %s\n", cfg.GetProjectPath())
-	fmt.Println("  2. Review the generated structure")
-	fmt.Println("  3. Start building your application!")
+	for i, step := range steps {
+		fmt.Printf("  %d. %s\n", i+1, step)
+	}
 	fmt.Println()
 
-	switch cfg.Architecture {
-	case config.LayeredArchitecture:
-		fmt.Println(InfoStyle.Render("Layered Architecture Notes:"))
-		fmt.Println("  - Presentation layer is in /internal/presentation")
-		fmt.Println("  - Business logic is in /internal/domain")
-		fmt.Println("  - Data access is in /internal/infrastructure")
-	case config.ModularArchitecture:
-		fmt.Println(InfoStyle.Render("Modular Architecture Notes:"))
-		fmt.Println("  - Each module is self-contained in /internal/modules")
-		fmt.Println("  - Shared code is in /internal/shared")
-		fmt.Println("  - Follow DDD principles for module boundaries")
-	case config.HexagonalArchitecture:
-		fmt.Println(InfoStyle.Render("Hexagonal Architecture Notes:"))
-		fmt.Println("  - Domain logic is in /internal/domain")
-		fmt.Println("  - Ports are in /internal/ports")
-		fmt.Println("  - Adapters are in /internal/adapters")
+	if len(manifest.Notes) > 0 {
+		fmt.Println(InfoStyle.Render(manifest.Name + " Notes:"))
+		for _, note := range manifest.Notes {
+			fmt.Printf("  - %s\n", note)
+		}
 	}
 
 	fmt.Println()
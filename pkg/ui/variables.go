@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/PickHD/pick-your-go/internal/template"
+
+	"github.com/charmbracelet/huh"
+)
+
+// variableField binds a template variable to the form field collecting its value
+type variableField struct {
+	variable template.Variable
+	text     string
+	enabled  bool
+	selected []string
+}
+
+// value returns the collected value in the shape expected by the variable type
+func (f *variableField) value() interface{} {
+	switch f.variable.Type {
+	case template.VariableTypeBool:
+		return f.enabled
+	case template.VariableTypeMultiSelect:
+		return f.selected
+	default:
+		return f.text
+	}
+}
+
+// buildVariableGroups creates one form group per template variable.
+// Fields are pre-filled with the variable defaults.
+func buildVariableGroups(variables []template.Variable) ([]*huh.Group, []*variableField) {
+	groups := make([]*huh.Group, 0, len(variables))
+	fields := make([]*variableField, 0, len(variables))
+
+	for _, v := range variables {
+		field := &variableField{variable: v}
+
+		// Pre-fill with the converted default value
+		if def, err := v.Convert(v.Default); err == nil {
+			switch value := def.(type) {
+			case bool:
+				field.enabled = value
+			case []string:
+				field.selected = value
+			case string:
+				field.text = value
+			}
+		}
+
+		var input huh.Field
+		switch v.Type {
+		case template.VariableTypeBool:
+			input = huh.NewConfirm().
+				Title(v.Prompt).
+				Description(v.Description).
+				Value(&field.enabled)
+		case template.VariableTypeSelect:
+			input = huh.NewSelect[string]().
+				Title(v.Prompt).
+				Description(v.Description).
+				Options(huh.NewOptions(v.Options...)...).
+				Value(&field.text)
+		case template.VariableTypeMultiSelect:
+			input = huh.NewMultiSelect[string]().
+				Title(v.Prompt).
+				Description(v.Description).
+				Options(huh.NewOptions(v.Options...)...).
+				Value(&field.selected)
+		default:
+			variable := v
+			input = huh.NewInput().
+				Title(v.Prompt).
+				Description(v.Description).
+				Prompt("> ").
+				Validate(func(s string) error {
+					return variable.ValidateString(s)
+				}).
+				Value(&field.text)
+		}
+
+		groups = append(groups, huh.NewGroup(input))
+		fields = append(fields, field)
+	}
+
+	return groups, fields
+}
+
+// collectVariableValues stores the form values into values
+func collectVariableValues(fields []*variableField, values map[string]interface{}) {
+	for _, field := range fields {
+		values[field.variable.Name] = field.value()
+	}
+}
+
+// RunVariablesForm prompts for template variables that have no value yet.
+// The collected values are added to values.
+func RunVariablesForm(variables []template.Variable, values map[string]interface{}) error {
+	if len(variables) == 0 {
+		return nil
+	}
+
+	groups, fields := buildVariableGroups(variables)
+	if err := huh.NewForm(groups...).Run(); err != nil {
+		return fmt.Errorf("form error: %w", err)
+	}
+
+	collectVariableValues(fields, values)
+	return nil
+}